@@ -0,0 +1,166 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Command webhook-fixture records GitCode webhook deliveries to disk and replays them.
+//
+//	webhook-fixture record -addr :8080 -dir testdata/recorded [-forward http://localhost:8888/hook] [-secret $SECRET] [-max-body-size 1048576]
+//	webhook-fixture replay -url http://localhost:8888/hook -secret $SECRET testdata/recorded/*.json
+//
+// The secret can also be given with the GITCODE_WEBHOOK_SECRET environment variable. When
+// recording, deliveries are only saved if their signature matches it; without a secret
+// every delivery is saved, so do not expose such a recorder to the internet.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/fixture"
+)
+
+const secretEnv = "GITCODE_WEBHOOK_SECRET"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "record":
+		err = record(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: webhook-fixture record|replay [flags]")
+	os.Exit(2)
+}
+
+func record(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	dir := fs.String("dir", "testdata/recorded", "directory to save deliveries into")
+	forward := fs.String("forward", "", "optional URL to forward deliveries to after recording")
+	secret := fs.String("secret", os.Getenv(secretEnv), "secret to verify deliveries with, none records every delivery")
+	maxBodySize := fs.Int64("max-body-size", 0, "largest body in bytes to accept, 0 for the default, negative for no limit")
+	_ = fs.Parse(args)
+
+	var next http.Handler
+	if *forward != "" {
+		target, err := url.Parse(*forward)
+		if err != nil {
+			return err
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		director := proxy.Director
+		proxy.Director = func(r *http.Request) {
+			director(r)
+			r.URL.Path = target.Path
+			r.Host = target.Host
+		}
+		next = proxy
+	}
+
+	rc, err := fixture.NewRecorder(*dir, next)
+	if err != nil {
+		return err
+	}
+	rc.OnError = func(r *http.Request, err error) {
+		log.Printf("record %s: %v", r.Header.Get(webhook.HeaderDelivery), err)
+	}
+	rc.MaxBodySize = *maxBodySize
+	if *secret != "" {
+		rc.Auth = new(webhook.GitCodeAuthentication)
+		if err = rc.Auth.SetSignKey([]byte(*secret)); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("no -secret or %s, recording deliveries without verifying them", secretEnv)
+	}
+
+	log.Printf("recording deliveries into %s, listening on %s", *dir, *addr)
+	srv := &http.Server{Addr: *addr, Handler: rc, ReadHeaderTimeout: 10 * time.Second}
+	return srv.ListenAndServe()
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := fs.String("url", "http://localhost:8888/", "URL of the handler to replay to")
+	secret := fs.String("secret", os.Getenv(secretEnv), "secret to sign deliveries with")
+	newDelivery := fs.Bool("new-delivery", false, "replace recorded delivery IDs")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each request")
+	_ = fs.Parse(args)
+
+	if *secret == "" {
+		return fmt.Errorf("missing -secret or %s", secretEnv)
+	}
+
+	var fixtures []*fixture.Fixture
+	for _, arg := range fs.Args() {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			loaded, err := fixture.LoadDir(arg)
+			if err != nil {
+				return err
+			}
+			fixtures = append(fixtures, loaded...)
+			continue
+		}
+
+		f, err := fixture.Load(arg)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, f)
+	}
+
+	rp := &fixture.Replayer{
+		Secret:      []byte(*secret),
+		Client:      &http.Client{Timeout: *timeout},
+		NewDelivery: *newDelivery,
+	}
+	failed := 0
+	for _, f := range fixtures {
+		resp, err := rp.Replay(context.Background(), f, *target)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		_ = resp.Body.Close()
+
+		fmt.Printf("%s\t%d\t%s\n", f.FileName(), resp.StatusCode, body)
+		if resp.StatusCode >= http.StatusBadRequest {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deliveries failed", failed, len(fixtures))
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Command webhook-relay receives GitCode webhook deliveries and forwards them, re-signed,
+// to the targets listed in a YAML file.
+//
+//	webhook-relay -addr :8080 -config relay.yaml
+//
+// The secret GitCode signs deliveries with is read from the GITCODE_WEBHOOK_SECRET
+// environment variable or the -secret flag. The config file looks like:
+//
+//	timeout: 10s
+//	max_attempts: 3
+//	async: true
+//	max_pending: 64
+//	retry_window: 1h
+//	targets:
+//	  - name: ci
+//	    url: http://ci.internal/hook
+//	    secret_env: CI_WEBHOOK_SECRET
+//	    events: ["Push Hook", "Merge Request Hook"]
+//	  - name: bot
+//	    url: http://bot.internal/hook
+//	    secret: plain-secret
+//	    timeout: 5s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/relay"
+)
+
+const secretEnv = "GITCODE_WEBHOOK_SECRET"
+
+type targetConfig struct {
+	Name        string        `yaml:"name"`
+	URL         string        `yaml:"url"`
+	Secret      string        `yaml:"secret"`
+	SecretEnv   string        `yaml:"secret_env"`
+	Events      []string      `yaml:"events"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"max_attempts"`
+}
+
+type config struct {
+	Timeout     time.Duration  `yaml:"timeout"`
+	MaxAttempts int            `yaml:"max_attempts"`
+	Async       bool           `yaml:"async"`
+	MaxPending  int            `yaml:"max_pending"`
+	RetryWindow time.Duration  `yaml:"retry_window"`
+	Targets     []targetConfig `yaml:"targets"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	path := flag.String("config", "relay.yaml", "YAML file listing the targets")
+	secret := flag.String("secret", os.Getenv(secretEnv), "secret GitCode signs deliveries with")
+	flag.Parse()
+
+	if err := run(*addr, *path, *secret); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadConfig(path string) (*config, []relay.Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := new(config)
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	targets := make([]relay.Target, 0, len(cfg.Targets))
+	for _, tc := range cfg.Targets {
+		s := tc.Secret
+		if tc.SecretEnv != "" {
+			if s = os.Getenv(tc.SecretEnv); s == "" {
+				return nil, nil, fmt.Errorf("target %s: %s is not set", tc.Name, tc.SecretEnv)
+			}
+		}
+		targets = append(targets, relay.Target{
+			Name:        tc.Name,
+			URL:         tc.URL,
+			Secret:      []byte(s),
+			EventTypes:  tc.Events,
+			Timeout:     tc.Timeout,
+			MaxAttempts: tc.MaxAttempts,
+		})
+	}
+	return cfg, targets, nil
+}
+
+func run(addr, path, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("missing -secret or %s", secretEnv)
+	}
+
+	cfg, targets, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	var auth webhook.GitCodeAuthentication
+	if err := auth.SetSignKey([]byte(secret)); err != nil {
+		return err
+	}
+
+	rl, err := relay.New(&auth, targets, relay.Options{
+		Client:      &http.Client{},
+		Timeout:     cfg.Timeout,
+		MaxAttempts: cfg.MaxAttempts,
+		Async:       cfg.Async,
+		MaxPending:  cfg.MaxPending,
+		RetryWindow: cfg.RetryWindow,
+		OnResult: func(r relay.Result) {
+			if r.Err != nil {
+				log.Printf("forward %s to %s failed after %d attempts: %v", r.Delivery, r.Target, r.Attempts, r.Err)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: addr, Handler: rl, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	log.Printf("relaying deliveries to %d targets, listening on %s", len(targets), addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	// wait for deliveries still being forwarded in async mode
+	return rl.Close(shutdown)
+}
@@ -37,6 +37,7 @@ type APIClient struct {
 	Issues       *IssuesService
 	PullRequests *PullRequestsService
 	Repository   *RepositoryService
+	Search       *SearchService
 	User         *UserService
 }
 
@@ -71,6 +72,7 @@ func NewAPIClientWithAuthorization(token []byte) *APIClient {
 	c.Issues = (*IssuesService)(&c.common)
 	c.PullRequests = (*PullRequestsService)(&c.common)
 	c.Repository = (*RepositoryService)(&c.common)
+	c.Search = (*SearchService)(&c.common)
 	c.User = (*UserService)(&c.common)
 
 	return c
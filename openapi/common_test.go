@@ -178,4 +178,44 @@ func TestNewRequestError(t *testing.T) {
 	assert.Equal(t, false, ok)
 	assert.Equal(t, (*RepositoryContent)(nil), result21)
 	assert.Equal(t, msg1, err.Error())
+
+	result22, ok, err := client.User.GetUser(context.Background(), "ibforu")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, (*User)(nil), result22)
+	assert.Equal(t, msg1, err.Error())
+
+	result23, ok, err := client.User.ListUserRepos(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*Repository)(nil), result23)
+	assert.Equal(t, msg1, err.Error())
+
+	result24, ok, err := client.User.ListEmails(context.Background())
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*UserEmail)(nil), result24)
+	assert.Equal(t, msg1, err.Error())
+
+	result25, ok, err := client.User.ListSSHKeys(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*SSHKey)(nil), result25)
+	assert.Equal(t, msg1, err.Error())
+
+	result26, ok, err := client.User.ListGPGKeys(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*GPGKey)(nil), result26)
+	assert.Equal(t, msg1, err.Error())
+
+	result27, ok, err := client.User.ListFollowers(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*User)(nil), result27)
+	assert.Equal(t, msg1, err.Error())
+
+	result28, ok, err := client.User.ListFollowing(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*User)(nil), result28)
+	assert.Equal(t, msg1, err.Error())
+
+	result29, ok, err := client.User.ListUserOrgs(context.Background(), "ibforu", "1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*Organization)(nil), result29)
+	assert.Equal(t, msg1, err.Error())
 }
@@ -23,6 +23,12 @@ import (
 //
 // api Docs: https://docs.gitcode.com/docs/openapi/repos/issues/#2-%e6%9b%b4%e6%96%b0issue
 func (s *IssuesService) UpdateIssue(ctx context.Context, owner, number string, issueContent *IssueRequest) (*Issue, bool, error) {
+	if issueContent != nil {
+		if err := issueContent.Validate(); err != nil {
+			return nil, false, err
+		}
+	}
+
 	urlStr := fmt.Sprintf("repos/%s/issues/%s", owner, number)
 	req, err := newRequest(s.api, http.MethodPatch, urlStr, issueContent)
 	if err != nil {
@@ -34,6 +40,21 @@ func (s *IssuesService) UpdateIssue(ctx context.Context, owner, number string, i
 	return updatedIssue, successCreated(resp), err
 }
 
+// GetIssue 获取仓库的某个Issue
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/repos/issues/
+func (s *IssuesService) GetIssue(ctx context.Context, owner, repo, number string) (*Issue, bool, error) {
+	urlStr := fmt.Sprintf("repos/%s/%s/issues/%s", owner, repo, number)
+	req, err := newRequest(s.api, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	issue := new(Issue)
+	resp, err := s.api.Do(ctx, req, issue)
+	return issue, successGetData(resp), err
+}
+
 // ListIssueLinkingPullRequests 获取 issue 关联的 pull requests
 //
 // api Docs: https://docs.gitcode.com/docs/openapi/repos/issues/#7-%e8%8e%b7%e5%8f%96-issue-%e5%85%b3%e8%81%94%e7%9a%84-pull-requests
@@ -18,6 +18,63 @@ import (
 	"net/url"
 )
 
+// IssueState Issue 状态，更新 Issue 时使用
+type IssueState string
+
+const (
+	IssueStateReopen IssueState = "reopen"
+	IssueStateClose  IssueState = "close"
+)
+
+func (s IssueState) Validate() error {
+	switch s {
+	case "", IssueStateReopen, IssueStateClose:
+		return nil
+	}
+	return newInvalidEnumError("state", string(s))
+}
+
+// IssueStage Issue 阶段
+type IssueStage string
+
+const (
+	IssueStageNew       IssueStage = "New"
+	IssueStageAccepted  IssueStage = "Accepted"
+	IssueStageCoding    IssueStage = "Coding"
+	IssueStageTesting   IssueStage = "Testing"
+	IssueStageRevising  IssueStage = "Revising"
+	IssueStageVerified  IssueStage = "Verified"
+	IssueStageCompleted IssueStage = "Completed"
+	IssueStageRejected  IssueStage = "Rejected"
+)
+
+func (s IssueStage) Validate() error {
+	switch s {
+	case "", IssueStageNew, IssueStageAccepted, IssueStageCoding, IssueStageTesting,
+		IssueStageRevising, IssueStageVerified, IssueStageCompleted, IssueStageRejected:
+		return nil
+	}
+	return newInvalidEnumError("issue_stage", string(s))
+}
+
+// IssueSeverity Issue 严重程度
+type IssueSeverity string
+
+const (
+	IssueSeveritySuggestion IssueSeverity = "Suggestion"
+	IssueSeverityMinor      IssueSeverity = "Minor"
+	IssueSeverityMajor      IssueSeverity = "Major"
+	IssueSeverityFatal      IssueSeverity = "Fatal"
+)
+
+func (s IssueSeverity) Validate() error {
+	switch s {
+	case "", IssueSeveritySuggestion, IssueSeverityMinor, IssueSeverityMajor, IssueSeverityFatal:
+		return nil
+	}
+	return newInvalidEnumError("issue_severity", string(s))
+}
+
 // Label represents a GitCode label on an Issue
 type Label struct {
 	Name  string `json:"name,omitempty"`
@@ -68,16 +125,27 @@ type PullRequestLinks struct {
 }
 
 type IssueRequest struct {
-	Repository    string `json:"repo,omitempty"` // 仓库地址
-	Title         string `json:"title,omitempty"`
-	Body          string `json:"body,omitempty"`
-	Labels        string `json:"labels,omitempty"`   // 用逗号分开的标签
-	Assignee      string `json:"assignee,omitempty"` // Issue负责人的 username
-	State         string `json:"state,omitempty"`
-	Milestone     int64  `json:"milestone,omitempty"`
-	SecurityHole  string `json:"security_hole,omitempty"`  // 是否是私有issue(默认为false)
-	IssueStage    string `json:"issue_stage,omitempty"`    // 严重程序（Accepted,Coding,Completed,New,Rejected,Revising,Testing,Verified）
-	IssueSeverity string `json:"issue_severity,omitempty"` // 优先级 （Suggestion,Minor,Major,Fatal）
+	Repository    string        `json:"repo,omitempty"` // 仓库地址
+	Title         string        `json:"title,omitempty"`
+	Body          string        `json:"body,omitempty"`
+	Labels        string        `json:"labels,omitempty"`   // 用逗号分开的标签
+	Assignee      string        `json:"assignee,omitempty"` // Issue负责人的 username
+	State         IssueState    `json:"state,omitempty"`
+	Milestone     int64         `json:"milestone,omitempty"`
+	SecurityHole  string        `json:"security_hole,omitempty"`  // 是否是私有issue(默认为false)
+	IssueStage    IssueStage    `json:"issue_stage,omitempty"`    // 阶段（Accepted,Coding,Completed,New,Rejected,Revising,Testing,Verified）
+	IssueSeverity IssueSeverity `json:"issue_severity,omitempty"` // 严重程度（Suggestion,Minor,Major,Fatal）
+}
+
+// Validate 在发送请求前校验枚举字段
+func (r *IssueRequest) Validate() error {
+	if err := r.State.Validate(); err != nil {
+		return err
+	}
+	if err := r.IssueStage.Validate(); err != nil {
+		return err
+	}
+	return r.IssueSeverity.Validate()
 }
 
 type IssueComment struct {
@@ -16,6 +16,7 @@ package openapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -89,3 +90,55 @@ func TestListIssueLinkingPullRequests(t *testing.T) {
 		assert.Equal(t, d1, d2)
 	}
 }
+
+func TestGetIssue(t *testing.T) {
+	client, mux, _ := mockServer(t)
+
+	issue := new(Issue)
+	_ = readTestdata(t, issuesTestDataDir+"issues_update.json", issue)
+
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/issues/1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(issue)
+	})
+
+	result, ok, err := client.Issues.GetIssue(context.Background(), owner, repo, "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	d1, _ := json.Marshal(issue)
+	d2, _ := json.Marshal(result)
+	assert.Equal(t, d1, d2)
+
+	_, ok, err = client.Issues.GetIssue(context.Background(), owner, repo, "2")
+	assert.Equal(t, false, ok)
+	assert.NotNil(t, err)
+}
+
+func TestIssueRequestValidate(t *testing.T) {
+	assert.Equal(t, nil, (&IssueRequest{}).Validate())
+	assert.Equal(t, nil, (&IssueRequest{
+		State:         IssueStateClose,
+		IssueStage:    IssueStageCoding,
+		IssueSeverity: IssueSeverityMajor,
+	}).Validate())
+
+	assert.True(t, errors.Is((&IssueRequest{State: "closed"}).Validate(), ErrInvalidEnumValue))
+	assert.True(t, errors.Is((&IssueRequest{IssueStage: "coding"}).Validate(), ErrInvalidEnumValue))
+	assert.True(t, errors.Is((&IssueRequest{IssueSeverity: "Critical"}).Validate(), ErrInvalidEnumValue))
+
+	client, mux, _ := mockServer(t)
+	called := false
+	mux.HandleFunc(prefixUrlPath+owner+"/issues/3", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	result, ok, err := client.Issues.UpdateIssue(context.Background(), owner, "3", &IssueRequest{
+		Repository:    repo,
+		IssueSeverity: "Blocker",
+	})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, (*Issue)(nil), result)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+	assert.Equal(t, false, called)
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // GetPullRequest 获取单个Pull Requests
@@ -39,6 +40,12 @@ func (s *PullRequestsService) GetPullRequest(ctx context.Context, owner, repo, n
 //
 // api Docs: https://docs.gitcode.com/docs/openapi/repos/pulls/#8-%e6%9b%b4%e6%96%b0pull-request%e4%bf%a1%e6%81%af
 func (s *PullRequestsService) UpdatePullRequest(ctx context.Context, owner, repo, number string, prContent *PullRequestRequest) (*PullRequest, bool, error) {
+	if prContent != nil {
+		if err := prContent.Validate(); err != nil {
+			return nil, false, err
+		}
+	}
+
 	urlStr := fmt.Sprintf("repos/%s/%s/pulls/%s", owner, repo, number)
 	req, err := newRequest(s.api, http.MethodPatch, urlStr, prContent)
 	if err != nil {
@@ -98,18 +105,87 @@ func (s *PullRequestsService) GetPullRequestChangeFiles(ctx context.Context, own
 // MergePullRequest 合并Pull Request
 //
 // api Docs: https://docs.gitcode.com/docs/openapi/repos/pulls/#2-%e5%90%88%e5%b9%b6pull-request
-func (s *PullRequestsService) MergePullRequest(ctx context.Context, owner, repo, number, mergeMethod string) (*PullRequestMergedResult, bool, error) {
-	urlStr := fmt.Sprintf("repos/%s/%s/pulls/%s/merge", owner, repo, number)
-	req, err := newRequest(s.api, http.MethodPut, urlStr, &PullRequestRequestMerge{
+func (s *PullRequestsService) MergePullRequest(ctx context.Context, owner, repo, number string, mergeMethod MergeMethod) (*PullRequestMergedResult, bool, error) {
+	return s.MergePullRequestWithOptions(ctx, owner, repo, number, &PullRequestRequestMerge{
 		Method: mergeMethod,
 	})
+}
+
+// MergePullRequestWithOptions 按指定的提交信息、分支清理和期望 SHA 合并Pull Request
+//
+// 合并被拒绝时返回 *MergeError，可用 errors.Is 判断是否为
+// ErrPullRequestNotMergeable、ErrPullRequestMergeConflict 或 ErrPullRequestHeadChanged
+//
+// 设置 opts.SHA 时先获取 Pull Request，head.sha 不一致则不发起合并并返回 ErrPullRequestHeadChanged。
+// 该 SHA 也随请求发送，但文档未说明 GitCode 是否校验它，比对与合并之间 head 仍可能变化，
+// 此时的 409 无法与冲突区分，按 ErrPullRequestMergeConflict 返回
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/repos/pulls/#2-%e5%90%88%e5%b9%b6pull-request
+func (s *PullRequestsService) MergePullRequestWithOptions(ctx context.Context, owner, repo, number string, opts *PullRequestRequestMerge) (*PullRequestMergedResult, bool, error) {
+	if opts == nil {
+		opts = &PullRequestRequestMerge{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	if opts.SHA != "" {
+		current, _, err := s.GetPullRequest(ctx, owner, repo, number)
+		if err != nil {
+			return nil, false, err
+		}
+		if current.Head == nil || current.Head.SHA == nil || *current.Head.SHA != opts.SHA {
+			head := ""
+			if current.Head != nil && current.Head.SHA != nil {
+				head = *current.Head.SHA
+			}
+			return nil, false, &MergeError{Reason: ErrPullRequestHeadChanged,
+				Message: fmt.Sprintf("head is %q, expected %q", head, opts.SHA)}
+		}
+	}
+
+	urlStr := fmt.Sprintf("repos/%s/%s/pulls/%s/merge", owner, repo, number)
+	req, err := newRequest(s.api, http.MethodPut, urlStr, opts)
 	if err != nil {
 		return nil, false, err
 	}
 
 	pr := new(PullRequestMergedResult)
 	resp, err := s.api.Do(ctx, req, pr)
-	return pr, successCreated(resp), err
+	if err = toMergeError(resp, pr, err); err != nil {
+		return pr, false, err
+	}
+	return pr, successCreated(resp), nil
+}
+
+// toMergeError 将合并接口的拒绝响应转换为 *MergeError，其余错误原样返回
+func toMergeError(resp *http.Response, result *PullRequestMergedResult, err error) error {
+	if resp == nil {
+		return err
+	}
+
+	if err == nil {
+		if result.Merged != nil && !*result.Merged {
+			msg := ""
+			if result.Message != nil {
+				msg = *result.Message
+			}
+			return &MergeError{StatusCode: resp.StatusCode, Reason: ErrPullRequestNotMergeable, Message: msg}
+		}
+		return nil
+	}
+
+	msg := err.Error()
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		return &MergeError{StatusCode: resp.StatusCode, Reason: ErrPullRequestMergeConflict, Message: msg}
+	case http.StatusMethodNotAllowed, http.StatusUnprocessableEntity:
+		if strings.Contains(strings.ToLower(msg), "conflict") {
+			return &MergeError{StatusCode: resp.StatusCode, Reason: ErrPullRequestMergeConflict, Message: msg}
+		}
+		return &MergeError{StatusCode: resp.StatusCode, Reason: ErrPullRequestNotMergeable, Message: msg}
+	}
+	return err
 }
 
 // ListPullRequestOperationLogs 获取某个Pull Request的操作日志
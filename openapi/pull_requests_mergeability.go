@@ -0,0 +1,127 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultMergeabilityInitialInterval = time.Second
+	defaultMergeabilityMaxInterval     = 30 * time.Second
+	defaultMergeabilityMultiplier      = 2
+)
+
+// MergeabilityOptions 控制 WaitForMergeability 的轮询行为，零值使用默认配置
+type MergeabilityOptions struct {
+	// ExpectedHeadSHA 非空时，只有 Pull Request 的 head SHA 与之一致才认为结果有效，
+	// 用于推送后等待 GitCode 基于新提交重新计算
+	ExpectedHeadSHA string
+	// InitialInterval 首次重试前的等待时间，默认 1s
+	InitialInterval time.Duration
+	// MaxInterval 两次轮询之间的最长等待时间，默认 30s
+	MaxInterval time.Duration
+	// Multiplier 每次重试后等待时间的增长倍数，默认 2
+	Multiplier float64
+}
+
+// Mergeability 描述 Pull Request 的可合并状态
+type Mergeability struct {
+	Mergeable     bool
+	CanMergeCheck bool
+	State         *MergeAbleState
+	HeadSHA       string
+	Attempts      int
+	PullRequest   *PullRequest
+}
+
+// WaitForMergeability 轮询 Pull Request 直到 GitCode 完成可合并性计算或 ctx 结束
+//
+// 第二个返回值表示可合并性是否已确定；ctx 结束时返回最后一次获取到的结果和 ctx.Err()
+func (s *PullRequestsService) WaitForMergeability(ctx context.Context, owner, repo, number string, opts *MergeabilityOptions) (*Mergeability, bool, error) {
+	if ctx == nil {
+		return nil, false, nilContentError
+	}
+	if opts == nil {
+		opts = &MergeabilityOptions{}
+	}
+
+	interval := opts.InitialInterval
+	if interval <= 0 {
+		interval = defaultMergeabilityInitialInterval
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultMergeabilityMaxInterval
+	}
+	multiplier := opts.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultMergeabilityMultiplier
+	}
+
+	var report *Mergeability
+	for attempt := 1; ; attempt++ {
+		pr, _, err := s.GetPullRequest(ctx, owner, repo, number)
+		if err != nil {
+			return report, false, err
+		}
+
+		report = newMergeability(pr, attempt)
+		if mergeabilityDetermined(pr, opts.ExpectedHeadSHA) {
+			return report, true, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return report, false, ctx.Err()
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * multiplier)
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+func newMergeability(pr *PullRequest, attempts int) *Mergeability {
+	m := &Mergeability{
+		State:       pr.MergeAbleState,
+		Attempts:    attempts,
+		PullRequest: pr,
+	}
+	if pr.MergeAble != nil {
+		m.Mergeable = *pr.MergeAble
+	}
+	if pr.CanMergeCheck != nil {
+		m.CanMergeCheck = *pr.CanMergeCheck
+	}
+	if pr.Head != nil && pr.Head.SHA != nil {
+		m.HeadSHA = *pr.Head.SHA
+	}
+	return m
+}
+
+func mergeabilityDetermined(pr *PullRequest, expectedHeadSHA string) bool {
+	if pr.MergeAble == nil || pr.CanMergeCheck == nil {
+		return false
+	}
+	if expectedHeadSHA == "" {
+		return true
+	}
+	return pr.Head != nil && pr.Head.SHA != nil && *pr.Head.SHA == expectedHeadSHA
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForMergeability(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	var calls int32
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/30", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			_, _ = w.Write([]byte(`{"number":30,"head":{"sha":"aaa"}}`))
+		case 2:
+			_, _ = w.Write([]byte(`{"number":30,"mergeable":true,"can_merge_check":true,"head":{"sha":"aaa"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"number":30,"mergeable":false,"can_merge_check":true,"mergeable_state":{"state":false},"head":{"sha":"bbb"}}`))
+		}
+	})
+
+	ctx := context.Background()
+	got, ok, err := client.PullRequests.WaitForMergeability(ctx, owner, repo, "30", &MergeabilityOptions{
+		ExpectedHeadSHA: "bbb",
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 3, got.Attempts)
+	assert.Equal(t, "bbb", got.HeadSHA)
+	assert.Equal(t, false, got.Mergeable)
+	assert.Equal(t, true, got.CanMergeCheck)
+	assert.Equal(t, false, *got.State.State)
+}
+
+func TestWaitForMergeabilityContextDone(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/31", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_, _ = w.Write([]byte(`{"number":31,"head":{"sha":"aaa"}}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	got, ok, err := client.PullRequests.WaitForMergeability(ctx, owner, repo, "31", &MergeabilityOptions{
+		InitialInterval: 5 * time.Millisecond,
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, "aaa", got.HeadSHA)
+	assert.True(t, got.Attempts >= 1)
+}
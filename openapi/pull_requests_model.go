@@ -13,7 +13,11 @@
 // limitations under the License.
 package openapi
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
 
 // PullRequest represents a GitHub pull request on a repository.
 type PullRequest struct {
@@ -72,20 +76,67 @@ type MergeAbleState struct {
 	State          *bool  `json:"state,omitempty"`
 }
 
+// PullRequestState Pull Request 状态
+type PullRequestState string
+
+const (
+	PullRequestStateOpen   PullRequestState = "open"
+	PullRequestStateClosed PullRequestState = "closed"
+)
+
+func (s PullRequestState) Validate() error {
+	switch s {
+	case "", PullRequestStateOpen, PullRequestStateClosed:
+		return nil
+	}
+	return newInvalidEnumError("state", string(s))
+}
+
+// MergeMethod Pull Request 合并方式
+type MergeMethod string
+
+const (
+	MergeMethodMerge  MergeMethod = "merge"
+	MergeMethodSquash MergeMethod = "squash"
+	MergeMethodRebase MergeMethod = "rebase"
+)
+
+func (m MergeMethod) Validate() error {
+	switch m {
+	case "", MergeMethodMerge, MergeMethodSquash, MergeMethodRebase:
+		return nil
+	}
+	return newInvalidEnumError("merge_method", string(m))
+}
+
 type PullRequestRequest struct {
-	ID              int64       `json:"id,omitempty"`
-	Title           string      `json:"title,omitempty"`
-	Body            string      `json:"body,omitempty"`
-	State           string      `json:"state,omitempty"`
-	Labels          string      `json:"labels,omitempty"`
-	MilestoneNumber string      `json:"milestone_number,omitempty"`
-	Draft           string      `json:"draft,omitempty"`
-	User            User        `json:"user,omitempty"`
-	Target          PullRequest `json:"target,omitempty"`
+	ID              int64            `json:"id,omitempty"`
+	Title           string           `json:"title,omitempty"`
+	Body            string           `json:"body,omitempty"`
+	State           PullRequestState `json:"state,omitempty"`
+	Labels          string           `json:"labels,omitempty"`
+	MilestoneNumber string           `json:"milestone_number,omitempty"`
+	Draft           string           `json:"draft,omitempty"`
+	User            User             `json:"user,omitempty"`
+	Target          PullRequest      `json:"target,omitempty"`
+}
+
+// Validate 在发送请求前校验枚举字段
+func (r *PullRequestRequest) Validate() error {
+	return r.State.Validate()
 }
 
 type PullRequestRequestMerge struct {
-	Method string `json:"merge_method,omitempty"`
+	Method            MergeMethod `json:"merge_method,omitempty"`
+	Title             string      `json:"title,omitempty"`               // 合并提交或 squash 提交的标题
+	Description       string      `json:"description,omitempty"`         // 合并提交或 squash 提交的说明
+	PruneSourceBranch bool        `json:"prune_source_branch,omitempty"` // 合并后删除源分支
+	SHA               string      `json:"sha,omitempty"`                 // 期望的源分支 head SHA，合并前由客户端比对，不一致时拒绝合并
+}
+
+// Validate 在发送请求前校验枚举字段
+func (r *PullRequestRequestMerge) Validate() error {
+	return r.Method.Validate()
 }
 
 type SimpleComment struct {
@@ -124,3 +175,31 @@ type PullRequestMergedResult struct {
 	Merged  *bool   `json:"merged,omitempty"`
 	Message *string `json:"message,omitempty"`
 }
+
+var (
+	// ErrPullRequestNotMergeable Pull Request 当前不可合并，如检查未通过或缺少评审
+	ErrPullRequestNotMergeable = errors.New("pull request is not mergeable")
+	// ErrPullRequestMergeConflict Pull Request 与目标分支存在冲突
+	ErrPullRequestMergeConflict = errors.New("pull request has merge conflicts")
+	// ErrPullRequestHeadChanged 源分支 head 与期望的 SHA 不一致，由客户端在合并前比对得出
+	ErrPullRequestHeadChanged = errors.New("pull request head does not match the expected sha")
+)
+
+// MergeError 合并 Pull Request 被拒绝时返回，可通过 errors.Is 判断具体原因
+type MergeError struct {
+	// StatusCode 合并接口的响应状态码，合并前由客户端拒绝时为 0
+	StatusCode int
+	Reason     error
+	Message    string
+}
+
+func (e *MergeError) Error() string {
+	if e.Message == "" {
+		return e.Reason.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Reason.Error(), e.Message)
+}
+
+func (e *MergeError) Unwrap() error {
+	return e.Reason
+}
@@ -16,6 +16,7 @@ package openapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -197,3 +198,122 @@ func TestMergePullRequest(t *testing.T) {
 	assert.Equal(t, true, ok)
 	assert.Equal(t, *want, *got)
 }
+
+func TestPullRequestEnumValidate(t *testing.T) {
+	assert.Equal(t, nil, MergeMethod("").Validate())
+	assert.Equal(t, nil, MergeMethodSquash.Validate())
+	assert.True(t, errors.Is(MergeMethod("fast-forward").Validate(), ErrInvalidEnumValue))
+
+	assert.Equal(t, nil, (&PullRequestRequest{State: PullRequestStateClosed}).Validate())
+	assert.True(t, errors.Is((&PullRequestRequest{State: "merged"}).Validate(), ErrInvalidEnumValue))
+
+	client, _, _ := mockServer(t)
+	ctx := context.Background()
+
+	got, ok, err := client.PullRequests.MergePullRequest(ctx, owner, repo, "19", "Squash")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, (*PullRequestMergedResult)(nil), got)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+
+	pr, ok, err := client.PullRequests.UpdatePullRequest(ctx, owner, repo, "19", &PullRequestRequest{State: "close"})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, (*PullRequest)(nil), pr)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+}
+
+func TestMergePullRequestWithOptions(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new(PullRequestMergedResult)
+	_ = readTestdata(t, prTestDataDir+"pull_requests_merge.json", want)
+
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/20/merge", func(w http.ResponseWriter, r *http.Request) {
+		got := new(PullRequestRequestMerge)
+		_ = json.NewDecoder(r.Body).Decode(got)
+		assert.Equal(t, PullRequestRequestMerge{
+			Method:            MergeMethodSquash,
+			Title:             "squash title",
+			Description:       "squash message",
+			PruneSourceBranch: true,
+			SHA:               "722fcd2c6ae57fbcfc32615ed67084cf0f600ae7",
+		}, *got)
+
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+	head := func(sha string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+			_ = json.NewEncoder(w).Encode(PullRequest{Head: &PullRequestBranch{SHA: &sha}})
+		}
+	}
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/20", head("722fcd2c6ae57fbcfc32615ed67084cf0f600ae7"))
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/21", head("722fcd2c6ae57fbcfc32615ed67084cf0f600ae7"))
+	merges21 := 0
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/21/merge", func(w http.ResponseWriter, r *http.Request) {
+		merges21++
+		http.Error(w, `{"error_message":"head sha mismatch"}`, http.StatusConflict)
+	})
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/22/merge", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error_message":"merge request has conflicts"}`, http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/23/merge", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error_message":"pipeline not passed"}`, http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc(prefixUrlPath+owner+"/"+repo+"/pulls/24/merge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_, _ = w.Write([]byte(`{"merged":false,"message":"not mergeable"}`))
+	})
+
+	ctx := context.Background()
+	got, ok, err := client.PullRequests.MergePullRequestWithOptions(ctx, owner, repo, "20", &PullRequestRequestMerge{
+		Method:            MergeMethodSquash,
+		Title:             "squash title",
+		Description:       "squash message",
+		PruneSourceBranch: true,
+		SHA:               "722fcd2c6ae57fbcfc32615ed67084cf0f600ae7",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, *want, *got)
+
+	// the head is compared before merging, the merge is not attempted
+	_, ok, err = client.PullRequests.MergePullRequestWithOptions(ctx, owner, repo, "21", &PullRequestRequestMerge{
+		SHA: "0000000000000000000000000000000000000000",
+	})
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestHeadChanged))
+	var mergeErr *MergeError
+	assert.True(t, errors.As(err, &mergeErr))
+	assert.Equal(t, 0, mergeErr.StatusCode)
+	assert.Equal(t, 0, merges21)
+
+	// a 409 can not be told apart from a conflict, even with a SHA
+	_, ok, err = client.PullRequests.MergePullRequestWithOptions(ctx, owner, repo, "21", &PullRequestRequestMerge{
+		SHA: "722fcd2c6ae57fbcfc32615ed67084cf0f600ae7",
+	})
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestMergeConflict))
+	assert.True(t, errors.As(err, &mergeErr))
+	assert.Equal(t, http.StatusConflict, mergeErr.StatusCode)
+
+	_, ok, err = client.PullRequests.MergePullRequestWithOptions(ctx, owner, repo, "21", nil)
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestMergeConflict))
+	assert.Equal(t, 2, merges21)
+
+	_, ok, err = client.PullRequests.MergePullRequest(ctx, owner, repo, "22", MergeMethodMerge)
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestMergeConflict))
+
+	_, ok, err = client.PullRequests.MergePullRequest(ctx, owner, repo, "23", MergeMethodMerge)
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestNotMergeable))
+
+	_, ok, err = client.PullRequests.MergePullRequest(ctx, owner, repo, "24", MergeMethodRebase)
+	assert.Equal(t, false, ok)
+	assert.True(t, errors.Is(err, ErrPullRequestNotMergeable))
+	assert.Equal(t, "pull request is not mergeable: not mergeable", err.Error())
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Repositories 搜索仓库，同时返回匹配的仓库总数，API 未返回总数时为 -1
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/search/
+func (s *SearchService) Repositories(ctx context.Context, q string, opts *SearchRepositoriesOptions) ([]*Repository, int, bool, error) {
+	query, err := opts.values(q)
+	if err != nil {
+		return nil, -1, false, err
+	}
+	req, err := newRequest(s.api, http.MethodGet, "search/repositories", query, RequestHandler{t: Query})
+	if err != nil {
+		return nil, -1, false, err
+	}
+
+	var repos []*Repository
+	resp, err := s.api.Do(ctx, req, &repos)
+	return repos, searchTotalCount(resp), successGetData(resp), err
+}
+
+// Issues 搜索 Issues，同时返回匹配的 Issue 总数，API 未返回总数时为 -1
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/search/
+func (s *SearchService) Issues(ctx context.Context, q string, opts *SearchIssuesOptions) ([]*Issue, int, bool, error) {
+	query, err := opts.values(q)
+	if err != nil {
+		return nil, -1, false, err
+	}
+	req, err := newRequest(s.api, http.MethodGet, "search/issues", query, RequestHandler{t: Query})
+	if err != nil {
+		return nil, -1, false, err
+	}
+
+	var issues []*Issue
+	resp, err := s.api.Do(ctx, req, &issues)
+	return issues, searchTotalCount(resp), successGetData(resp), err
+}
+
+// Users 搜索用户，同时返回匹配的用户总数，API 未返回总数时为 -1
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/search/
+func (s *SearchService) Users(ctx context.Context, q string, opts *SearchUsersOptions) ([]*User, int, bool, error) {
+	query, err := opts.values(q)
+	if err != nil {
+		return nil, -1, false, err
+	}
+	req, err := newRequest(s.api, http.MethodGet, "search/users", query, RequestHandler{t: Query})
+	if err != nil {
+		return nil, -1, false, err
+	}
+
+	var users []*User
+	resp, err := s.api.Do(ctx, req, &users)
+	return users, searchTotalCount(resp), successGetData(resp), err
+}
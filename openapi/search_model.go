@@ -0,0 +1,184 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// headerTotalCount 分页接口返回匹配总数的响应头
+const headerTotalCount = "total_count"
+
+// SearchSort 搜索结果的排序字段，为空时按匹配度排序
+type SearchSort string
+
+const (
+	SearchSortBestMatch SearchSort = ""
+
+	// 仓库搜索可用
+	SearchSortLastPush SearchSort = "last_push_at"
+	SearchSortStars    SearchSort = "stars_count"
+	SearchSortForks    SearchSort = "forks_count"
+	SearchSortWatches  SearchSort = "watches_count"
+
+	// Issue 搜索可用
+	SearchSortCreated SearchSort = "created_at"
+	SearchSortUpdated SearchSort = "updated_at"
+	SearchSortNotes   SearchSort = "notes_count"
+
+	// 用户搜索可用
+	SearchSortJoined SearchSort = "joined_at"
+)
+
+// SearchOrder 搜索结果的排列顺序
+type SearchOrder string
+
+const (
+	SearchOrderAsc  SearchOrder = "asc"
+	SearchOrderDesc SearchOrder = "desc"
+)
+
+func (o SearchOrder) Validate() error {
+	switch o {
+	case "", SearchOrderAsc, SearchOrderDesc:
+		return nil
+	}
+	return newInvalidEnumError("order", string(o))
+}
+
+// SearchOptions 各类搜索共用的排序与分页参数
+type SearchOptions struct {
+	Sort    SearchSort
+	Order   SearchOrder
+	Page    int // 从 1 开始，为 0 时使用服务端默认值
+	PerPage int // 每页数量，最大 100，为 0 时使用服务端默认值
+}
+
+func (o *SearchOptions) values(q string, sorts ...SearchSort) (*url.Values, error) {
+	query := &url.Values{"q": []string{q}}
+	if o == nil {
+		return query, nil
+	}
+
+	if err := o.Order.Validate(); err != nil {
+		return nil, err
+	}
+	if o.Sort != SearchSortBestMatch {
+		valid := false
+		for _, s := range sorts {
+			valid = valid || o.Sort == s
+		}
+		if !valid {
+			return nil, newInvalidEnumError("sort", string(o.Sort))
+		}
+		query.Set("sort", string(o.Sort))
+	}
+	if o.Order != "" {
+		query.Set("order", string(o.Order))
+	}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	return query, nil
+}
+
+// SearchRepositoriesOptions 搜索仓库的参数
+type SearchRepositoriesOptions struct {
+	SearchOptions
+
+	Owner    string // 仓库所属空间地址，如组织名
+	Language string // 仓库语言
+	Topic    string // 仓库主题，只搜索带有该主题的仓库
+	Fork     *bool  // 是否只搜索 fork 仓库，nil 表示不限制
+}
+
+func (o *SearchRepositoriesOptions) values(q string) (*url.Values, error) {
+	if o == nil {
+		return (*SearchOptions)(nil).values(q)
+	}
+
+	query, err := o.SearchOptions.values(q, SearchSortLastPush, SearchSortStars, SearchSortForks, SearchSortWatches)
+	if err != nil {
+		return nil, err
+	}
+	setIfNotEmpty(query, "owner", o.Owner)
+	setIfNotEmpty(query, "language", o.Language)
+	setIfNotEmpty(query, "topic", o.Topic)
+	if o.Fork != nil {
+		query.Set("fork", strconv.FormatBool(*o.Fork))
+	}
+	return query, nil
+}
+
+// SearchIssuesOptions 搜索 Issue 的参数
+type SearchIssuesOptions struct {
+	SearchOptions
+
+	Owner string // Issue 所属仓库的空间地址，如组织名，用于在整个组织内搜索
+	Repo  string // 仓库路径，格式为 owner/repo
+	State string // Issue 状态，如 open、progressing、closed、rejected
+	Label string // 用逗号分开的标签
+}
+
+func (o *SearchIssuesOptions) values(q string) (*url.Values, error) {
+	if o == nil {
+		return (*SearchOptions)(nil).values(q)
+	}
+
+	query, err := o.SearchOptions.values(q, SearchSortCreated, SearchSortUpdated, SearchSortNotes)
+	if err != nil {
+		return nil, err
+	}
+	setIfNotEmpty(query, "owner", o.Owner)
+	setIfNotEmpty(query, "repo", o.Repo)
+	setIfNotEmpty(query, "state", o.State)
+	setIfNotEmpty(query, "label", o.Label)
+	return query, nil
+}
+
+// SearchUsersOptions 搜索用户的参数
+type SearchUsersOptions struct {
+	SearchOptions
+}
+
+func (o *SearchUsersOptions) values(q string) (*url.Values, error) {
+	if o == nil {
+		return (*SearchOptions)(nil).values(q)
+	}
+
+	return o.SearchOptions.values(q, SearchSortJoined)
+}
+
+// searchTotalCount 返回响应头 total_count 中的匹配总数，响应中没有该值时返回 -1
+func searchTotalCount(resp *http.Response) int {
+	if resp == nil {
+		return -1
+	}
+	n, err := strconv.Atoi(resp.Header.Get(headerTotalCount))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func setIfNotEmpty(query *url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
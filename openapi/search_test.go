@@ -0,0 +1,148 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSearchRepositories(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*Repository)
+	_ = readTestdata(t, searchTestDataDir+"search_repositories.json", want)
+
+	mux.HandleFunc("/search/repositories", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fork=false&order=desc&owner=ibforuorg&page=2&per_page=50&q=security&sort=stars_count&topic=cve", r.URL.RawQuery)
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		w.Header().Set(headerTotalCount, "120")
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	fork := false
+	got, total, ok, err := client.Search.Repositories(context.Background(), "security", &SearchRepositoriesOptions{
+		SearchOptions: SearchOptions{Sort: SearchSortStars, Order: SearchOrderDesc, Page: 2, PerPage: 50},
+		Owner:         "ibforuorg",
+		Topic:         "cve",
+		Fork:          &fork,
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 120, total)
+	d1, _ := json.Marshal(*want)
+	d2, _ := json.Marshal(got)
+	assert.Equal(t, d1, d2)
+}
+
+func TestSearchRepositoriesByTopic(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	mux.HandleFunc("/search/repositories", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "owner=ibforuorg&q=&topic=cve", r.URL.RawQuery)
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	got, _, ok, err := client.Search.Repositories(context.Background(), "", &SearchRepositoriesOptions{
+		Owner: "ibforuorg",
+		Topic: "cve",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []*Repository{}, got)
+}
+
+func TestSearchIssues(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*Issue)
+	_ = readTestdata(t, searchTestDataDir+"search_issues.json", want)
+
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "CVE-2024-12345", r.URL.Query().Get("q"))
+		assert.Equal(t, "open", r.URL.Query().Get("state"))
+		assert.Equal(t, "ibforuorg", r.URL.Query().Get("owner"))
+		assert.Equal(t, "", r.URL.Query().Get("repo"))
+		assert.Equal(t, "", r.URL.Query().Get("sort"))
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		w.Header().Set(headerTotalCount, "3")
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, total, ok, err := client.Search.Issues(context.Background(), "CVE-2024-12345", &SearchIssuesOptions{
+		Owner: "ibforuorg",
+		State: "open",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 3, total)
+	d1, _ := json.Marshal(*want)
+	d2, _ := json.Marshal(got)
+	assert.Equal(t, d1, d2)
+}
+
+func TestSearchUsers(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*User)
+	_ = readTestdata(t, searchTestDataDir+"search_users.json", want)
+
+	mux.HandleFunc("/search/users", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "q=ibforu", r.URL.RawQuery)
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, total, ok, err := client.Search.Users(context.Background(), "ibforu", nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, -1, total)
+	assert.Equal(t, *want, got)
+}
+
+func TestSearchOptionsValidate(t *testing.T) {
+
+	client, _, _ := mockServer(t)
+	ctx := context.Background()
+
+	repos, _, ok, err := client.Search.Repositories(ctx, "a", &SearchRepositoriesOptions{
+		SearchOptions: SearchOptions{Sort: SearchSortNotes},
+	})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*Repository)(nil), repos)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+
+	issues, _, ok, err := client.Search.Issues(ctx, "a", &SearchIssuesOptions{
+		SearchOptions: SearchOptions{Order: "up"},
+	})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*Issue)(nil), issues)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+
+	users, _, ok, err := client.Search.Users(ctx, "a", &SearchUsersOptions{
+		SearchOptions: SearchOptions{Sort: SearchSortStars},
+	})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, ([]*User)(nil), users)
+	assert.True(t, errors.Is(err, ErrInvalidEnumValue))
+}
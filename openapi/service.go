@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -39,8 +40,16 @@ const (
 var (
 	nilContentError               = errors.New("request context should be non-nil")
 	respReceiverNotAnPointerError = errors.New("response's receiver should be an pointer")
+
+	// ErrInvalidEnumValue 请求中的枚举字段取值非法，请求不会被发送
+	ErrInvalidEnumValue = errors.New("invalid enum value")
 )
 
+// newInvalidEnumError 构造枚举字段取值非法的错误，可通过 errors.Is(err, ErrInvalidEnumValue) 判断
+func newInvalidEnumError(field, value string) error {
+	return fmt.Errorf("%w: %s=%q", ErrInvalidEnumValue, field, value)
+}
+
 type RequestHandlerType string
 
 const (
@@ -120,3 +129,5 @@ type PullRequestsService service
 type RepositoryService service
 
 type UserService service
+
+type SearchService service
@@ -36,6 +36,7 @@ const (
 	prTestDataDir     = testDataDir + string(os.PathSeparator) + "pr" + string(os.PathSeparator)
 	reposTestDataDir  = testDataDir + string(os.PathSeparator) + "repos" + string(os.PathSeparator)
 	userTestDataDir   = testDataDir + string(os.PathSeparator) + "user" + string(os.PathSeparator)
+	searchTestDataDir = testDataDir + string(os.PathSeparator) + "search" + string(os.PathSeparator)
 )
 
 // setup sets up a test HTTP server along with a github.api that is
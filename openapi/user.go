@@ -15,7 +15,9 @@ package openapi
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // GetUserInfo 获取授权用户的资料
@@ -31,3 +33,122 @@ func (s *UserService) GetUserInfo(ctx context.Context) (*User, bool, error) {
 	resp, err := s.api.Do(ctx, req, userInfo)
 	return userInfo, successGetData(resp), err
 }
+
+// GetUser 获取一个用户
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#1-%e8%8e%b7%e5%8f%96%e4%b8%80%e4%b8%aa%e7%94%a8%e6%88%b7
+func (s *UserService) GetUser(ctx context.Context, login string) (*User, bool, error) {
+	urlStr := fmt.Sprintf("users/%s", login)
+	req, err := newRequest(s.api, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	user := new(User)
+	resp, err := s.api.Do(ctx, req, user)
+	return user, successGetData(resp), err
+}
+
+// ListUserRepos 列出某个用户的公开仓库
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#3-%e5%88%97%e5%87%ba%e6%9f%90%e4%b8%aa%e7%94%a8%e6%88%b7%e7%9a%84%e5%85%ac%e5%bc%80%e4%bb%93%e5%ba%93
+func (s *UserService) ListUserRepos(ctx context.Context, login, page string) ([]*Repository, bool, error) {
+	urlStr := fmt.Sprintf("users/%s/repos", login)
+	req, err := newRequest(s.api, http.MethodGet, urlStr,
+		&url.Values{"page": []string{page}, "per_page": []string{"100"}}, RequestHandler{t: Query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var repos []*Repository
+	resp, err := s.api.Do(ctx, req, &repos)
+	return repos, successGetData(resp), err
+}
+
+// ListEmails 获取授权用户的全部邮箱
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#4-%e8%8e%b7%e5%8f%96%e6%8e%88%e6%9d%83%e7%94%a8%e6%88%b7%e7%9a%84%e5%85%a8%e9%83%a8%e9%82%ae%e7%ae%b1
+func (s *UserService) ListEmails(ctx context.Context) ([]*UserEmail, bool, error) {
+	req, err := newRequest(s.api, http.MethodGet, "emails", nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var emails []*UserEmail
+	resp, err := s.api.Do(ctx, req, &emails)
+	return emails, successGetData(resp), err
+}
+
+// ListSSHKeys 列出某个用户的公钥
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#5-%e5%88%97%e5%87%ba%e6%8c%87%e5%ae%9a%e7%94%a8%e6%88%b7%e7%9a%84%e6%89%80%e6%9c%89%e5%85%ac%e9%92%a5
+func (s *UserService) ListSSHKeys(ctx context.Context, login, page string) ([]*SSHKey, bool, error) {
+	urlStr := fmt.Sprintf("users/%s/keys", login)
+	req, err := newRequest(s.api, http.MethodGet, urlStr,
+		&url.Values{"page": []string{page}, "per_page": []string{"100"}}, RequestHandler{t: Query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var keys []*SSHKey
+	resp, err := s.api.Do(ctx, req, &keys)
+	return keys, successGetData(resp), err
+}
+
+// ListGPGKeys 列出某个用户的 GPG 公钥
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#6-%e5%88%97%e5%87%ba%e6%8c%87%e5%ae%9a%e7%94%a8%e6%88%b7%e7%9a%84%e6%89%80%e6%9c%89-gpg-%e5%85%ac%e9%92%a5
+func (s *UserService) ListGPGKeys(ctx context.Context, login, page string) ([]*GPGKey, bool, error) {
+	urlStr := fmt.Sprintf("users/%s/gpg_keys", login)
+	req, err := newRequest(s.api, http.MethodGet, urlStr,
+		&url.Values{"page": []string{page}, "per_page": []string{"100"}}, RequestHandler{t: Query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var keys []*GPGKey
+	resp, err := s.api.Do(ctx, req, &keys)
+	return keys, successGetData(resp), err
+}
+
+// ListFollowers 列出某个用户的关注者
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#7-%e5%88%97%e5%87%ba%e6%8c%87%e5%ae%9a%e7%94%a8%e6%88%b7%e7%9a%84%e5%85%b3%e6%b3%a8%e8%80%85
+func (s *UserService) ListFollowers(ctx context.Context, login, page string) ([]*User, bool, error) {
+	return s.listUsers(ctx, fmt.Sprintf("users/%s/followers", login), page)
+}
+
+// ListFollowing 列出某个用户正在关注的用户
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/users/#8-%e5%88%97%e5%87%ba%e6%8c%87%e5%ae%9a%e7%94%a8%e6%88%b7%e6%ad%a3%e5%9c%a8%e5%85%b3%e6%b3%a8%e7%9a%84%e7%94%a8%e6%88%b7
+func (s *UserService) ListFollowing(ctx context.Context, login, page string) ([]*User, bool, error) {
+	return s.listUsers(ctx, fmt.Sprintf("users/%s/following", login), page)
+}
+
+func (s *UserService) listUsers(ctx context.Context, urlStr, page string) ([]*User, bool, error) {
+	req, err := newRequest(s.api, http.MethodGet, urlStr,
+		&url.Values{"page": []string{page}, "per_page": []string{"100"}}, RequestHandler{t: Query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var users []*User
+	resp, err := s.api.Do(ctx, req, &users)
+	return users, successGetData(resp), err
+}
+
+// ListUserOrgs 列出某个用户所属的组织
+//
+// api Docs: https://docs.gitcode.com/docs/openapi/orgs/#2-%e5%88%97%e5%87%ba%e7%94%a8%e6%88%b7%e6%89%80%e5%b1%9e%e7%9a%84%e7%bb%84%e7%bb%87
+func (s *UserService) ListUserOrgs(ctx context.Context, login, page string) ([]*Organization, bool, error) {
+	urlStr := fmt.Sprintf("users/%s/orgs", login)
+	req, err := newRequest(s.api, http.MethodGet, urlStr,
+		&url.Values{"page": []string{page}, "per_page": []string{"100"}}, RequestHandler{t: Query})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var orgs []*Organization
+	resp, err := s.api.Do(ctx, req, &orgs)
+	return orgs, successGetData(resp), err
+}
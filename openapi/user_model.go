@@ -33,3 +33,29 @@ type User struct {
 type Permission struct {
 	Admin *bool `json:"admin,omitempty"`
 }
+
+// UserEmail represents an email address of the authenticated user.
+type UserEmail struct {
+	Email *string  `json:"email,omitempty"`
+	State *string  `json:"state,omitempty"`
+	Scope []string `json:"scope,omitempty"`
+}
+
+// SSHKey represents a public SSH key of a user.
+type SSHKey struct {
+	ID        *int64     `json:"id,omitempty"`
+	Key       *string    `json:"key,omitempty"`
+	Title     *string    `json:"title,omitempty"`
+	URL       *string    `json:"url,omitempty"`
+	CreatedAt *Timestamp `json:"created_at,omitempty"`
+}
+
+// GPGKey represents a GPG public key of a user.
+type GPGKey struct {
+	ID        *int64     `json:"id,omitempty"`
+	KeyID     *string    `json:"key_id,omitempty"`
+	PublicKey *string    `json:"public_key,omitempty"`
+	Emails    []string   `json:"emails,omitempty"`
+	CreatedAt *Timestamp `json:"created_at,omitempty"`
+	ExpiresAt *Timestamp `json:"expires_at,omitempty"`
+}
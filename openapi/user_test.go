@@ -38,3 +38,140 @@ func TestGetUserInfo(t *testing.T) {
 	assert.Equal(t, true, ok)
 	assert.Equal(t, *want, *got)
 }
+
+func TestGetUser(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new(User)
+	_ = readTestdata(t, userTestDataDir+"user.json", want)
+
+	mux.HandleFunc("/users/ibforu", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, ok, err := client.User.GetUser(context.Background(), "ibforu")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, *want, *got)
+}
+
+func TestListUserRepos(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*Repository)
+	_ = readTestdata(t, userTestDataDir+"user_repos.json", want)
+
+	mux.HandleFunc("/users/ibforu/repos", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "page=2&per_page=100", r.URL.RawQuery)
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, ok, err := client.User.ListUserRepos(context.Background(), "ibforu", "2")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	d1, _ := json.Marshal(*want)
+	d2, _ := json.Marshal(got)
+	assert.Equal(t, d1, d2)
+}
+
+func TestListEmails(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*UserEmail)
+	_ = readTestdata(t, userTestDataDir+"user_emails.json", want)
+
+	mux.HandleFunc("/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, ok, err := client.User.ListEmails(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, *want, got)
+}
+
+func TestListUserKeys(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	wantSSH := new([]*SSHKey)
+	_ = readTestdata(t, userTestDataDir+"user_ssh_keys.json", wantSSH)
+	wantGPG := new([]*GPGKey)
+	_ = readTestdata(t, userTestDataDir+"user_gpg_keys.json", wantGPG)
+
+	mux.HandleFunc("/users/ibforu/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(wantSSH)
+	})
+	mux.HandleFunc("/users/ibforu/gpg_keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(wantGPG)
+	})
+
+	ctx := context.Background()
+	gotSSH, ok, err := client.User.ListSSHKeys(ctx, "ibforu", "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	d1, _ := json.Marshal(*wantSSH)
+	d2, _ := json.Marshal(gotSSH)
+	assert.Equal(t, d1, d2)
+
+	gotGPG, ok, err := client.User.ListGPGKeys(ctx, "ibforu", "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	d3, _ := json.Marshal(*wantGPG)
+	d4, _ := json.Marshal(gotGPG)
+	assert.Equal(t, d3, d4)
+}
+
+func TestListFollowersAndFollowing(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*User)
+	_ = readTestdata(t, userTestDataDir+"user_followers.json", want)
+
+	mux.HandleFunc("/users/ibforu/followers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+	mux.HandleFunc("/users/ibforu/following", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	ctx := context.Background()
+	got, ok, err := client.User.ListFollowers(ctx, "ibforu", "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, *want, got)
+
+	got, ok, err = client.User.ListFollowing(ctx, "ibforu", "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 0, len(got))
+}
+
+func TestListUserOrgs(t *testing.T) {
+
+	client, mux, _ := mockServer(t)
+
+	want := new([]*Organization)
+	_ = readTestdata(t, userTestDataDir+"user_orgs.json", want)
+
+	mux.HandleFunc("/users/ibforu/orgs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentTypeName, headerContentTypeJsonValue)
+		_ = json.NewEncoder(w).Encode(want)
+	})
+
+	got, ok, err := client.User.ListUserOrgs(context.Background(), "ibforu", "1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, *want, got)
+}
@@ -16,7 +16,21 @@ package webhook
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnknownEventType is returned by Parse when the X-GitCode-Event header
+	// names an event this package does not model. The payload is still returned.
+	ErrUnknownEventType = errors.New("unknown webhook event type")
+	// ErrPayloadParse is returned by Parse when the payload can not be decoded
+	// into the event struct selected by the X-GitCode-Event header.
+	ErrPayloadParse = errors.New("failed to parse webhook payload")
 )
 
 type GitCodeAccessor struct {
@@ -24,44 +38,280 @@ type GitCodeAccessor struct {
 	PR     *PullRequestEvent
 	Note   *NoteEvent
 	Push   *PushEvent
+
+	TagPush    *TagPushEvent
+	Release    *ReleaseEvent
+	Pipeline   *PipelineEvent
+	Job        *JobEvent
+	WikiPage   *WikiPageEvent
+	Member     *MemberEvent
+	Repository *RepositoryEvent
+
+	unknownFields  UnknownFieldsFunc
+	maxPayloadSize int64
+	observer       Observer
 }
 
+// Values of the X-GitCode-Event header for the events this package decodes.
 const (
-	pushEvent        = "Push Hook"
-	issueEvent       = "Issue Hook"
-	pullRequestEvent = "Merge Request Hook"
-	noteEvent        = "Note Hook"
+	EventTypePush        = "Push Hook"
+	EventTypeIssue       = "Issue Hook"
+	EventTypePullRequest = "Merge Request Hook"
+	EventTypeNote        = "Note Hook"
+	EventTypeTagPush     = "Tag Push Hook"
+	EventTypeRelease     = "Release Hook"
+	EventTypePipeline    = "Pipeline Hook"
+	EventTypeJob         = "Job Hook"
+	EventTypeWikiPage    = "Wiki Page Hook"
+	EventTypeMember      = "Member Hook"
+	EventTypeRepository  = "Repository Update Hook"
 )
 
-func (a *GitCodeAccessor) GetAccessor(w http.ResponseWriter, r *http.Request) (any, *bytes.Buffer, *string, *string) {
-	payload, err := ReadPayload(w, r)
-	if err != nil {
-		return nil, nil, nil, nil
-	}
+const (
+	pushEvent        = EventTypePush
+	issueEvent       = EventTypeIssue
+	pullRequestEvent = EventTypePullRequest
+	noteEvent        = EventTypeNote
+	tagPushEvent     = EventTypeTagPush
+	releaseEvent     = EventTypeRelease
+	pipelineEvent    = EventTypePipeline
+	jobEvent         = EventTypeJob
+	wikiPageEvent    = EventTypeWikiPage
+	memberEvent      = EventTypeMember
+	repositoryEvent  = EventTypeRepository
+)
 
-	eventGUID := r.Header.Get(headerEventGUID)
-	eventType := r.Header.Get(headerEventType)
+// UnknownFieldsFunc receives the paths, such as "project.id" or "commits[].added", of the
+// payload fields that the event struct for eventType does not model, sorted.
+type UnknownFieldsFunc func(eventType string, fields []string)
+
+// SetStrict makes Parse pass the payload fields that the event structs do not model to f,
+// which surfaces GitCode schema changes instead of silently dropping data. The event is
+// still decoded and returned, real deliveries carry many such fields. A nil f disables it.
+func (a *GitCodeAccessor) SetStrict(f UnknownFieldsFunc) {
+	a.unknownFields = f
+}
+
+// SetMaxPayloadSize limits the bodies read by Parse, see AuthConfig.MaxBodySize for the
+// meaning of 0 and negative values.
+func (a *GitCodeAccessor) SetMaxPayloadSize(n int64) {
+	a.maxPayloadSize = n
+}
+
+// SetObserver makes Parse report its result to o, nil disables it.
+func (a *GitCodeAccessor) SetObserver(o Observer) {
+	a.observer = o
+}
 
+// newEvent returns an empty event of the type carried in the X-GitCode-Event header,
+// or nil when the event type is not supported.
+func newEvent(eventType string) Event {
 	switch eventType {
 	case issueEvent:
-		a.Issues = new(IssueEvent)
-		_ = json.Unmarshal(payload.Bytes(), a.Issues)
-		return a.Issues, payload, &eventType, &eventGUID
+		return new(IssueEvent)
 	case pullRequestEvent:
-		a.PR = new(PullRequestEvent)
-		_ = json.Unmarshal(payload.Bytes(), a.PR)
-		return a.PR, payload, &eventType, &eventGUID
+		return new(PullRequestEvent)
 	case noteEvent:
-		a.Note = new(NoteEvent)
-		_ = json.Unmarshal(payload.Bytes(), a.Note)
-		return a.Note, payload, &eventType, &eventGUID
+		return new(NoteEvent)
 	case pushEvent:
-		a.Push = new(PushEvent)
-		_ = json.Unmarshal(payload.Bytes(), a.Push)
-		return a.Push, payload, &eventType, &eventGUID
+		return new(PushEvent)
+	case tagPushEvent:
+		return new(TagPushEvent)
+	case releaseEvent:
+		return new(ReleaseEvent)
+	case pipelineEvent:
+		return new(PipelineEvent)
+	case jobEvent:
+		return new(JobEvent)
+	case wikiPageEvent:
+		return new(WikiPageEvent)
+	case memberEvent:
+		return new(MemberEvent)
+	case repositoryEvent:
+		return new(RepositoryEvent)
 	default:
-		// do nothing
+		return nil
+	}
+}
+
+// decodeEvent decodes payload into the event struct for eventType and, if report is
+// non-nil, passes it the fields of payload that struct does not model.
+func decodeEvent(eventType string, payload []byte, report UnknownFieldsFunc) (Event, error) {
+	event := newEvent(eventType)
+	if event == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, eventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrPayloadParse, eventType, err)
+	}
+	if report != nil {
+		if fields := unknownFields(payload, reflect.TypeOf(event)); len(fields) > 0 {
+			report(eventType, fields)
+		}
+	}
+	return event, nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields returns the sorted paths of the object keys in payload that decoding into
+// a value of type t ignores.
+func unknownFields(payload []byte, t reflect.Type) []string {
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil
+	}
+
+	found := map[string]struct{}{}
+	walkUnknownFields("", v, t, found)
+	fields := make([]string, 0, len(found))
+	for f := range found {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func walkUnknownFields(path string, v any, t reflect.Type, found map[string]struct{}) {
+	t = indirectType(t)
+	if t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if t.Kind() == reflect.Map {
+			for key, value := range v {
+				walkUnknownFields(joinFieldPath(path, key), value, t.Elem(), found)
+			}
+			return
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := jsonFields(t, map[string]reflect.Type{})
+		for key, value := range v {
+			ft, ok := fields[strings.ToLower(key)]
+			if !ok {
+				found[joinFieldPath(path, key)] = struct{}{}
+				continue
+			}
+			walkUnknownFields(joinFieldPath(path, key), value, ft, found)
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, value := range v {
+				walkUnknownFields(path+"[]", value, t.Elem(), found)
+			}
+		}
+	}
+}
+
+// jsonFields maps the lower-cased JSON names of the fields of struct t, including those of
+// embedded structs, to their types, encoding/json matches names case-insensitively.
+func jsonFields(t reflect.Type, fields map[string]reflect.Type) map[string]reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft := indirectType(f.Type); ft.Kind() == reflect.Struct {
+				jsonFields(ft, fields)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Parse reads the request body and decodes it according to the X-GitCode-Event header.
+// It returns the event, the raw payload, the event type and the delivery GUID.
+//
+// The error wraps ErrUnknownEventType for unsupported events, ErrPayloadParse for
+// malformed payloads, or is the error returned by ReadPayloadLimit.
+func (a *GitCodeAccessor) Parse(w http.ResponseWriter, r *http.Request) (Event, *bytes.Buffer, *string, *string, error) {
+	if r == nil {
+		return nil, nil, nil, nil, errorNilRequest
+	}
+
+	eventGUID := r.Header.Get(headerEventGUID)
+	eventType := r.Header.Get(headerEventType)
+
+	payload, err := ReadPayloadLimit(w, r, payloadLimit(a.maxPayloadSize))
+	if err != nil {
+		a.parsed(r, eventType, eventGUID, err)
+		return nil, nil, &eventType, &eventGUID, err
+	}
+	if payload == nil {
+		payload = &bytes.Buffer{}
+	}
+
+	event, err := decodeEvent(eventType, payload.Bytes(), a.unknownFields)
+	a.parsed(r, eventType, eventGUID, err)
+	if err != nil {
+		return nil, payload, &eventType, &eventGUID, err
 	}
 
-	return nil, payload, &eventType, &eventGUID
+	switch e := event.(type) {
+	case *IssueEvent:
+		a.Issues = e
+	case *PullRequestEvent:
+		a.PR = e
+	case *NoteEvent:
+		a.Note = e
+	case *PushEvent:
+		a.Push = e
+	case *TagPushEvent:
+		a.TagPush = e
+	case *ReleaseEvent:
+		a.Release = e
+	case *PipelineEvent:
+		a.Pipeline = e
+	case *JobEvent:
+		a.Job = e
+	case *WikiPageEvent:
+		a.WikiPage = e
+	case *MemberEvent:
+		a.Member = e
+	case *RepositoryEvent:
+		a.Repository = e
+	}
+
+	return event, payload, &eventType, &eventGUID, nil
+}
+
+func (a *GitCodeAccessor) parsed(r *http.Request, eventType, eventGUID string, err error) {
+	if a.observer != nil {
+		a.observer.Parsed(r.Context(), eventType, eventGUID, err)
+	}
+}
+
+// GetAccessor is Parse without the error: the event is nil when the event type is unknown
+// or the payload can not be decoded.
+//
+// Deprecated: use Parse, which reports why no event was returned.
+func (a *GitCodeAccessor) GetAccessor(w http.ResponseWriter, r *http.Request) (Event, *bytes.Buffer, *string, *string) {
+	event, payload, eventType, eventGUID, _ := a.Parse(w, r)
+	return event, payload, eventType, eventGUID
 }
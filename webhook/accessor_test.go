@@ -16,6 +16,8 @@ package webhook
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"github.com/opensourceways/go-gitcode/openapi"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -53,6 +55,94 @@ func TestGetAccessor(t *testing.T) {
 	assert.Equal(t, "fasgasd", *got3)
 }
 
+func TestParse(t *testing.T) {
+	newRequest := func(eventType string, data []byte) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/parse", bytes.NewReader(data))
+		req.Header.Set(headerEventType, eventType)
+		req.Header.Set(headerEventGUID, "guid")
+		return req
+	}
+
+	a := new(GitCodeAccessor)
+	got, payload, eventType, _, err := a.Parse(httptest.NewRecorder(), newRequest("Dummy Hook", []byte("{}")))
+	assert.Equal(t, nil, got)
+	assert.True(t, errors.Is(err, ErrUnknownEventType))
+	assert.Equal(t, "{}", payload.String())
+	assert.Equal(t, "Dummy Hook", *eventType)
+
+	got, payload, _, _, err = a.Parse(httptest.NewRecorder(), newRequest(issueEvent, []byte("{\"object_attributes\": []}")))
+	assert.Equal(t, nil, got)
+	assert.True(t, errors.Is(err, ErrPayloadParse))
+	assert.Equal(t, "{\"object_attributes\": []}", payload.String())
+	assert.Equal(t, (*IssueEvent)(nil), a.Issues)
+
+	got, _, _, _, err = a.Parse(nil, nil)
+	assert.Equal(t, nil, got)
+	assert.Equal(t, errorNilRequest, err)
+
+	data := readWebHookTestdata(t, webhookTestDataDir+"issues_create.json", nil)
+	got, _, _, _, err = a.Parse(httptest.NewRecorder(), newRequest(issueEvent, data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, a.Issues, got)
+
+	var reported []string
+	a.SetStrict(func(eventType string, fields []string) {
+		assert.Equal(t, issueEvent, eventType)
+		reported = fields
+	})
+	got, _, _, _, err = a.Parse(httptest.NewRecorder(), newRequest(issueEvent,
+		[]byte(`{"uuid": "1", "bogus": {"a": 1}, "labels": [{"name": "x", "extra": 1}], "project": {"id": 2}}`)))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "1", *got.(*IssueEvent).UUID)
+	assert.Equal(t, []string{"bogus", "labels[].extra", "project.id"}, reported)
+
+	reported = nil
+	_, _, _, _, _ = a.Parse(httptest.NewRecorder(), newRequest(issueEvent, []byte(`{"UUID": "1"}`)))
+	assert.Equal(t, []string(nil), reported)
+}
+
+func TestParseStrictFixtures(t *testing.T) {
+	fixtures := map[string]string{
+		"issues_create.json":     issueEvent,
+		"issues_note.json":       noteEvent,
+		"job.json":               jobEvent,
+		"member.json":            memberEvent,
+		"pipeline.json":          pipelineEvent,
+		"pr_create.json":         pullRequestEvent,
+		"pr_note.json":           noteEvent,
+		"pr_update.json":         pullRequestEvent,
+		"push_code.json":         pushEvent,
+		"release.json":           releaseEvent,
+		"repository_update.json": repositoryEvent,
+		"tag_push.json":          tagPushEvent,
+		"wiki_page.json":         wikiPageEvent,
+	}
+
+	// real deliveries carry fields the structs do not model, strict mode reports them
+	// but must still return the same event as the default mode
+	for file, eventType := range fixtures {
+		data := readWebHookTestdata(t, webhookTestDataDir+file, nil)
+		want, err := decodeEvent(eventType, data, nil)
+		assert.Equal(t, nil, err, file)
+
+		var reported []string
+		a := new(GitCodeAccessor)
+		a.SetStrict(func(_ string, fields []string) {
+			reported = fields
+		})
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/strict", bytes.NewReader(data))
+		req.Header.Set(headerEventType, eventType)
+		got, _, _, _, err := a.Parse(httptest.NewRecorder(), req)
+		assert.Equal(t, nil, err, file)
+		assert.Equal(t, want, got, file)
+		if file == "member.json" {
+			assert.Empty(t, reported, file)
+		} else {
+			assert.Contains(t, reported, "project.id", file)
+		}
+	}
+}
+
 func createIssue(t *testing.T) {
 	want := GitCodeAccessor{Issues: new(IssueEvent)}
 	data := readWebHookTestdata(t, webhookTestDataDir+"issues_create.json", want.Issues)
@@ -117,22 +207,57 @@ func pushCode(t *testing.T) {
 	assert.Equal(t, "fasgasd", *got3)
 
 	pr, _ := got1.(*PushEvent)
-	assert.Equal(t, (*string)(nil), pr.GetAction())
+	assert.Equal(t, "create", *pr.GetAction())
+	assert.Equal(t, (*string)(nil), pr.GetActionDetail())
 	assert.Equal(t, (*string)(nil), pr.GetState())
 	assert.Equal(t, "ibforuorg", *pr.GetOrg())
 	assert.Equal(t, "org-repo-role-member-manage", *pr.GetRepo())
 	assert.Equal(t, "https://gitcode.com/ibforuorg/org-repo-role-member-manage", *pr.GetHtmlURL())
 	assert.Equal(t, "dev", *pr.GetBase())
+	assert.Equal(t, "2f03691536b8ef5ee2710b8605b9d16ccc96b52f", *pr.GetHead())
+	assert.Equal(t, "2f03691536b8ef5ee2710b8605b9d16ccc96b52f", *pr.GetID())
 	assert.Equal(t, "ibforu", *pr.GetAuthor())
+	assert.Equal(t, "refs/heads/dev", *pr.Ref)
+	assert.Equal(t, 1, len(pr.Commits))
+	assert.Equal(t, "push event\n", *pr.Commits[0].Message)
+	assert.Equal(t, "fengchao", *pr.Commits[0].Author.Name)
+	assert.Equal(t, true, pr.IsBranchCreate())
+	assert.Equal(t, false, pr.IsBranchDelete())
+	assert.Equal(t, false, pr.IsTag())
 
 	pr = new(PushEvent)
 	rt := reflect.TypeOf(pr)
 	n := rt.NumMethod()
 	for i := 0; i < n; i++ {
 		rm := rt.Method(i)
+		if rm.Type.NumOut() != 1 || rm.Type.Out(0) != reflect.TypeOf((*string)(nil)) {
+			continue
+		}
 		ret := rm.Func.Call([]reflect.Value{reflect.ValueOf(pr)})
 		assert.Equal(t, (*string)(nil), ret[0].Interface())
 	}
+	assert.Equal(t, false, pr.IsBranchCreate())
+	assert.Equal(t, false, pr.IsBranchDelete())
+	assert.Equal(t, (*string)(nil), pr.GetBase())
+}
+
+func TestPushEventRefChanges(t *testing.T) {
+	sha := "2f03691536b8ef5ee2710b8605b9d16ccc96b52f"
+	zero, ref, tag := zeroSHA, "refs/heads/feature/x", "refs/tags/v1.0.0"
+
+	p := &PushEvent{Before: &sha, After: &zero, Ref: &ref}
+	assert.Equal(t, true, p.IsBranchDelete())
+	assert.Equal(t, false, p.IsBranchCreate())
+	assert.Equal(t, "delete", *p.GetAction())
+	assert.Equal(t, "feature/x", *p.GetBase())
+
+	p = &PushEvent{Before: &sha, After: &sha, Ref: &ref}
+	assert.Equal(t, "update", *p.GetAction())
+
+	p = &PushEvent{Before: &zero, After: &sha, Ref: &tag}
+	assert.Equal(t, true, p.IsTag())
+	assert.Equal(t, false, p.IsBranchCreate())
+	assert.Equal(t, "v1.0.0", *p.GetBase())
 }
 
 func createPR(t *testing.T) {
@@ -169,15 +294,89 @@ func createPR(t *testing.T) {
 	assert.Equal(t, "****", *pr.GetAuthor())
 	assert.Equal(t, "2024-10-26T10:32:40+08:00", *pr.GetCreateTime())
 	assert.Equal(t, "2024-10-26T10:32:41+08:00", *pr.GetUpdateTime())
+	assert.Equal(t, "12314124", *pr.GetHeadSHA())
+	assert.Equal(t, "[WIP]42141241", *pr.GetTitle())
+	assert.Equal(t, "241241241231", *pr.GetDescription())
+	assert.Equal(t, true, pr.IsDraft())
+	assert.Equal(t, false, pr.IsNewCommitPush())
+	assert.Equal(t, true, pr.Changes.Has("title"))
+	assert.Equal(t, 0, len(pr.LabelsAdded()))
 
 	pr = new(PullRequestEvent)
 	rt := reflect.TypeOf(pr)
 	n := rt.NumMethod()
 	for i := 0; i < n; i++ {
 		rm := rt.Method(i)
+		if rm.Type.NumOut() != 1 || rm.Type.Out(0) != reflect.TypeOf((*string)(nil)) {
+			continue
+		}
 		ret := rm.Func.Call([]reflect.Value{reflect.ValueOf(pr)})
 		assert.Equal(t, (*string)(nil), ret[0].Interface())
 	}
+	assert.Equal(t, false, pr.IsDraft())
+	assert.Equal(t, false, pr.IsNewCommitPush())
+	assert.Equal(t, []*openapi.Label(nil), pr.LabelsAdded())
+	assert.Equal(t, []*openapi.Label(nil), pr.LabelsRemoved())
+}
+
+func TestPRPartHelpers(t *testing.T) {
+	note := new(NoteEvent)
+	_ = readWebHookTestdata(t, webhookTestDataDir+"pr_note.json", note)
+
+	assert.Equal(t, "412312231", *note.PR.GetHeadSHA())
+	assert.Equal(t, "[WIP]42141241", *note.PR.GetTitle())
+	assert.Equal(t, "241241241231", *note.PR.GetDescription())
+	assert.Equal(t, true, note.PR.IsDraft())
+
+	// a pull request event carrying only the merge_request block
+	pr := &PullRequestEvent{PR: note.PR}
+	assert.Equal(t, "412312231", *pr.GetHeadSHA())
+	assert.Equal(t, "[WIP]42141241", *pr.GetTitle())
+	assert.Equal(t, "241241241231", *pr.GetDescription())
+	assert.Equal(t, true, pr.IsDraft())
+
+	var part *PRPart
+	assert.Equal(t, false, part.IsDraft())
+	assert.Equal(t, (*string)(nil), part.GetHeadSHA())
+	assert.Equal(t, (*string)(nil), part.GetTitle())
+	assert.Equal(t, (*string)(nil), part.GetDescription())
+	assert.Equal(t, []*openapi.User(nil), part.GetReviewers())
+	assert.Equal(t, []*openapi.User(nil), part.GetAssignees())
+
+	// the merge_request block is used when object_attributes has no assignees
+	assignee, reviewer := "assignee", "reviewer"
+	part = &PRPart{
+		Assignees: []*openapi.User{{UserName: &assignee}},
+		Reviewers: []*openapi.User{{UserName: &reviewer}},
+	}
+	pr = &PullRequestEvent{Attributes: &Attributes{}, PR: part}
+	assert.Equal(t, "assignee", *pr.GetAssignees()[0].UserName)
+	assert.Equal(t, "reviewer", *pr.GetReviewers()[0].UserName)
+}
+
+func TestPullRequestEventChanges(t *testing.T) {
+	pr := new(PullRequestEvent)
+	_ = readWebHookTestdata(t, webhookTestDataDir+"pr_update.json", pr)
+
+	assert.Equal(t, "update", *pr.GetAction())
+	assert.Equal(t, "56785678", *pr.GetHeadSHA())
+	assert.Equal(t, true, pr.IsNewCommitPush())
+	assert.Equal(t, false, pr.IsDraft())
+	assert.Equal(t, "reviewer", *pr.GetReviewers()[0].UserName)
+	assert.Equal(t, "assignee", *pr.GetAssignees()[0].UserName)
+
+	added := pr.LabelsAdded()
+	assert.Equal(t, 1, len(added))
+	assert.Equal(t, "lgtm", added[0].Name)
+	removed := pr.LabelsRemoved()
+	assert.Equal(t, 1, len(removed))
+	assert.Equal(t, "needs-review", removed[0].Name)
+
+	pr.Changes[changedLabels] = &Change{Previous: json.RawMessage("{}")}
+	_, _, ok, err := pr.Changes.Labels()
+	assert.Equal(t, true, ok)
+	assert.NotNil(t, err)
+	assert.Equal(t, []*openapi.Label(nil), pr.LabelsAdded())
 }
 
 func notePR(t *testing.T) {
@@ -303,3 +502,176 @@ retry:
 	t.Error(path + " not found")
 	return nil
 }
+
+func TestParseMoreEvents(t *testing.T) {
+	sha := "2f03691536b8ef5ee2710b8605b9d16ccc96b52f"
+	cases := []struct {
+		eventType string
+		file      string
+		empty     any
+		field     func(a *GitCodeAccessor) any
+		want      map[string]string
+	}{
+		{
+			eventType: tagPushEvent,
+			file:      "tag_push.json",
+			empty:     new(TagPushEvent),
+			field:     func(a *GitCodeAccessor) any { return a.TagPush },
+			want: map[string]string{
+				"GetAction":       "create",
+				"GetActionDetail": "first release",
+				"GetOrg":          "ibforuorg",
+				"GetRepo":         "test1",
+				"GetHtmlURL":      "https://gitcode.com/ibforuorg/test1",
+				"GetBase":         "v1.0.0",
+				"GetHead":         sha,
+				"GetID":           sha,
+				"GetAuthor":       "ibforu",
+			},
+		},
+		{
+			eventType: releaseEvent,
+			file:      "release.json",
+			empty:     new(ReleaseEvent),
+			field:     func(a *GitCodeAccessor) any { return a.Release },
+			want: map[string]string{
+				"GetAction":     "create",
+				"GetOrg":        "ibforuorg",
+				"GetRepo":       "test1",
+				"GetHtmlURL":    "https://gitcode.com/ibforuorg/test1/releases/v1.0.0",
+				"GetBase":       "v1.0.0",
+				"GetHead":       sha,
+				"GetID":         "101",
+				"GetAuthor":     "ibforu",
+				"GetCreateTime": "2024-11-09T10:00:00+08:00",
+				"GetUpdateTime": "2024-11-09T10:00:05+08:00",
+			},
+		},
+		{
+			eventType: pipelineEvent,
+			file:      "pipeline.json",
+			empty:     new(PipelineEvent),
+			field:     func(a *GitCodeAccessor) any { return a.Pipeline },
+			want: map[string]string{
+				"GetActionDetail": "merge_request_event",
+				"GetState":        "success",
+				"GetOrg":          "ibforuorg",
+				"GetRepo":         "test1",
+				"GetHtmlURL":      "https://gitcode.com/ibforuorg/test1/pipelines/2001",
+				"GetBase":         "dev",
+				"GetHead":         sha,
+				"GetNumber":       "7",
+				"GetID":           "2001",
+				"GetAuthor":       "ibforu",
+				"GetCreateTime":   "2024-11-09T10:01:00+08:00",
+				"GetUpdateTime":   "2024-11-09T10:02:03+08:00",
+			},
+		},
+		{
+			eventType: jobEvent,
+			file:      "job.json",
+			empty:     new(JobEvent),
+			field:     func(a *GitCodeAccessor) any { return a.Job },
+			want: map[string]string{
+				"GetActionDetail": "script_failure",
+				"GetState":        "failed",
+				"GetOrg":          "ibforuorg",
+				"GetRepo":         "test1",
+				"GetHtmlURL":      "https://gitcode.com/ibforuorg/test1/jobs/3001",
+				"GetBase":         "dev",
+				"GetHead":         sha,
+				"GetID":           "3001",
+				"GetAuthor":       "ibforu",
+				"GetCreateTime":   "2024-11-09T10:01:10+08:00",
+				"GetUpdateTime":   "2024-11-09T10:01:23+08:00",
+			},
+		},
+		{
+			eventType: wikiPageEvent,
+			file:      "wiki_page.json",
+			empty:     new(WikiPageEvent),
+			field:     func(a *GitCodeAccessor) any { return a.WikiPage },
+			want: map[string]string{
+				"GetAction":       "update",
+				"GetActionDetail": "update home page",
+				"GetOrg":          "ibforuorg",
+				"GetRepo":         "test1",
+				"GetHtmlURL":      "https://gitcode.com/ibforuorg/test1/wiki/home",
+				"GetID":           "home",
+				"GetAuthor":       "ibforu",
+				"GetCreateTime":   "2024-11-01T08:00:00+08:00",
+				"GetUpdateTime":   "2024-11-09T10:03:00+08:00",
+			},
+		},
+		{
+			eventType: memberEvent,
+			file:      "member.json",
+			empty:     new(MemberEvent),
+			field:     func(a *GitCodeAccessor) any { return a.Member },
+			want: map[string]string{
+				"GetAction":     "user_add_to_team",
+				"GetState":      "Developer",
+				"GetOrg":        "ibforuorg",
+				"GetRepo":       "test1",
+				"GetID":         "4001",
+				"GetAuthor":     "contributor",
+				"GetCreateTime": "2024-11-09T10:04:00+08:00",
+				"GetUpdateTime": "2024-11-09T10:04:00+08:00",
+			},
+		},
+		{
+			eventType: repositoryEvent,
+			file:      "repository_update.json",
+			empty:     new(RepositoryEvent),
+			field:     func(a *GitCodeAccessor) any { return a.Repository },
+			want: map[string]string{
+				"GetAction":     "repository_update",
+				"GetOrg":        "ibforuorg",
+				"GetRepo":       "test1",
+				"GetHtmlURL":    "https://gitcode.com/ibforuorg/test1",
+				"GetBase":       "refs/heads/dev",
+				"GetHead":       sha,
+				"GetAuthor":     "ibforu",
+				"GetCreateTime": "2024-11-09T10:05:00+08:00",
+				"GetUpdateTime": "2024-11-09T10:05:00+08:00",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		data := readWebHookTestdata(t, webhookTestDataDir+c.file, nil)
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/more", bytes.NewReader(data))
+		req.Header.Set(headerEventType, c.eventType)
+		req.Header.Set(headerEventGUID, "guid")
+
+		a := new(GitCodeAccessor)
+		got, _, eventType, _, err := a.Parse(httptest.NewRecorder(), req)
+		assert.Equal(t, nil, err, c.eventType)
+		assert.Equal(t, c.eventType, *eventType)
+		assert.Equal(t, reflect.TypeOf(c.empty), reflect.TypeOf(got), c.eventType)
+		assert.Equal(t, got, c.field(a), c.eventType)
+		assert.Equal(t, c.eventType, EventTypeOf(got))
+
+		rt := reflect.TypeOf(got)
+		for i := 0; i < rt.NumMethod(); i++ {
+			rm := rt.Method(i)
+			ret := rm.Func.Call([]reflect.Value{reflect.ValueOf(got)})[0].Interface().(*string)
+			if want, ok := c.want[rm.Name]; ok {
+				if assert.NotNil(t, ret, c.eventType+"."+rm.Name) {
+					assert.Equal(t, want, *ret, c.eventType+"."+rm.Name)
+				}
+			} else {
+				assert.Equal(t, (*string)(nil), ret, c.eventType+"."+rm.Name)
+			}
+
+			ret = rm.Func.Call([]reflect.Value{reflect.ValueOf(c.empty)})[0].Interface().(*string)
+			assert.Equal(t, (*string)(nil), ret, c.eventType+"."+rm.Name)
+		}
+	}
+
+	before, after := sha, zeroSHA
+	tag := &TagPushEvent{Before: &before, After: &after}
+	assert.Equal(t, refActionDelete, *tag.GetAction())
+	tag.After = &before
+	assert.Equal(t, refActionUpdate, *tag.GetAction())
+}
@@ -17,12 +17,15 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -32,10 +35,21 @@ var (
 )
 
 type GitCodeAuthentication struct {
-	payload   *bytes.Buffer
-	eventType string
-	eventGUID string
-	signKey   string
+	payload    *bytes.Buffer
+	eventType  string
+	eventGUID  string
+	matchedKey int // index+1 of the key that verified the request, 0 if none
+	redelivery bool
+	recorded   bool // eventGUID was added to deliveryStore by this request
+
+	signKey       string
+	signKeys      []string // additional keys accepted during secret rotation
+	legacyToken   bool
+	deliveryStore DeliveryStore
+	duplicateMode DuplicateDeliveryMode
+	maxClockSkew  time.Duration
+	cfg           AuthConfig
+	observer      Observer
 }
 
 func (a *GitCodeAuthentication) SetSignKey(token []byte) error {
@@ -43,9 +57,107 @@ func (a *GitCodeAuthentication) SetSignKey(token []byte) error {
 		return errorNilToken
 	}
 	a.signKey = string(token)
+	a.signKeys = nil
 	return nil
 }
 
+// SetSignKeys sets all secrets that are currently accepted, so a secret can be rotated
+// without downtime: add the new secret, update the GitCode webhook, then drop the old one.
+// GetMatchedKeyIndex reports which of them verified a request.
+func (a *GitCodeAuthentication) SetSignKeys(tokens ...[]byte) error {
+	if len(tokens) == 0 {
+		return errorNilToken
+	}
+
+	keys := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if len(token) == 0 {
+			return errorNilToken
+		}
+		keys = append(keys, string(token))
+	}
+	a.signKey, a.signKeys = keys[0], keys[1:]
+	return nil
+}
+
+// SetLegacyToken accepts requests that carry the secret in plain text in the X-GitCode-Token
+// header when no X-GitCode-Signature-256 header is present.
+func (a *GitCodeAuthentication) SetLegacyToken(enabled bool) {
+	a.legacyToken = enabled
+}
+
+// GetMatchedKeyIndex returns the position, in the order given to SetSignKeys, of the
+// secret that verified the last request, or -1 if the request was not verified.
+func (a *GitCodeAuthentication) GetMatchedKeyIndex() int {
+	return a.matchedKey - 1
+}
+
+// SetDeliveryStore makes Auth record the X-GitCode-Delivery ID of every verified request
+// and treat an ID that store has already seen according to mode. A nil store disables the check.
+// A caller that fails to handle a request it answers with an error status, so that GitCode
+// retries it, must call ForgetDelivery first, or the retry would be treated as a duplicate.
+func (a *GitCodeAuthentication) SetDeliveryStore(store DeliveryStore, mode DuplicateDeliveryMode) {
+	a.deliveryStore = store
+	a.duplicateMode = mode
+}
+
+// SetMaxClockSkew makes Auth require an X-GitCode-Timestamp header, in Unix milliseconds,
+// that is at most d away from the local clock. A d <= 0 disables the check.
+func (a *GitCodeAuthentication) SetMaxClockSkew(d time.Duration) {
+	a.maxClockSkew = d
+}
+
+// IsRedelivery reports whether the last request carried a delivery ID that had already been
+// seen. It can only be true with DuplicateDeliveryFlag; DuplicateDeliveryReject fails Auth instead.
+func (a *GitCodeAuthentication) IsRedelivery() bool {
+	return a.redelivery
+}
+
+// ForgetDelivery removes the delivery ID recorded by the last successful Auth from the
+// DeliveryStore. It does nothing when that request did not record its ID.
+func (a *GitCodeAuthentication) ForgetDelivery() error {
+	if !a.recorded {
+		return nil
+	}
+	a.recorded = false
+	return a.deliveryStore.Forget(a.eventGUID)
+}
+
+// SetConfig replaces the User-Agent, Content-Type and body size checks of Auth.
+func (a *GitCodeAuthentication) SetConfig(cfg AuthConfig) {
+	a.cfg = cfg
+}
+
+// SetObserver makes Auth report received and rejected requests to o, nil disables it.
+func (a *GitCodeAuthentication) SetObserver(o Observer) {
+	a.observer = o
+}
+
+func (a *GitCodeAuthentication) keys() []string {
+	return append([]string{a.signKey}, a.signKeys...)
+}
+
+// config returns a copy that shares the configuration but none of the per-request state.
+func (a *GitCodeAuthentication) config() GitCodeAuthentication {
+	return GitCodeAuthentication{
+		signKey:       a.signKey,
+		signKeys:      a.signKeys,
+		legacyToken:   a.legacyToken,
+		deliveryStore: a.deliveryStore,
+		duplicateMode: a.duplicateMode,
+		maxClockSkew:  a.maxClockSkew,
+		cfg:           a.cfg,
+		observer:      a.observer,
+	}
+}
+
+// Clone returns a copy of a with the same secrets and checks and no per-request state.
+// GitCodeAuthentication is not safe for concurrent use, so handlers Clone it per request.
+func (a *GitCodeAuthentication) Clone() *GitCodeAuthentication {
+	c := a.config()
+	return &c
+}
+
 func (a *GitCodeAuthentication) GetPayload() *bytes.Buffer {
 	return a.payload
 }
@@ -59,11 +171,13 @@ func (a *GitCodeAuthentication) GetEventGUID() string {
 }
 
 const (
-	headerEventType      = "X-GitCode-Event"
-	headerEventGUID      = "X-GitCode-Delivery"
-	headerEventToken     = "X-GitCode-Signature-256"
+	headerEventType      = HeaderEvent
+	headerEventGUID      = HeaderDelivery
+	headerEventToken     = HeaderSignature
+	headerLegacyToken    = HeaderToken
+	headerEventTimestamp = HeaderTimestamp
 	headerUserAgent      = "User-Agent"
-	headerUserAgentValue = "git-gitcode-hook"
+	headerUserAgentValue = UserAgent
 
 	headerContentTypeName      = "Content-Type"
 	headerContentTypeJsonValue = "application/json"
@@ -72,70 +186,205 @@ const (
 
 	// error message constants
 	bodyReadErrorMessage           = "400 Bad Request: Failed to read request body"
+	bodyTooLargeErrorMessage       = "413 Request Entity Too Large: Request body is too large"
 	headerContentTypeErrorMessage  = "400 Bad Request: Hook only accepts content-type: application/json"
 	headerEventErrorMessage        = "400 Bad Request: Missing X-GitCode-Event Header"
 	headerUserAgentErrorMessage    = "400 Bad Request: Invalid User-Agent Header"
 	headerEmptyTokenErrorMessage   = "401 Unauthorized: Missing X-GitCode-Token"
 	headerInvalidTokenErrorMessage = "403 Forbidden: Invalid X-GitCode-Token"
+	headerTimestampErrorMessage    = "400 Bad Request: Missing or invalid X-GitCode-Timestamp Header"
+	clockSkewErrorMessage          = "401 Unauthorized: X-GitCode-Timestamp is outside the allowed clock skew"
+	duplicateDeliveryErrorMessage  = "409 Conflict: Duplicate X-GitCode-Delivery"
+	deliveryStoreErrorMessage      = "500 Internal Server Error: Failed to record X-GitCode-Delivery"
 )
 
+// Auth verifies a webhook request and reads its payload.
+//
+// On failure the error is an *AuthError. The second return value reports whether the
+// response is still unwritten, so the caller must reply itself; Auth writes the error
+// response whenever it has a non-nil http.ResponseWriter and request.
 func (a *GitCodeAuthentication) Auth(w http.ResponseWriter, r *http.Request) (error, bool) {
+	if a.observer != nil && r != nil {
+		a.observer.Received(r.Context(), r)
+	}
+
+	if err := a.auth(w, r); err != nil {
+		if a.observer != nil && r != nil {
+			a.observer.Rejected(r.Context(), r, err)
+		}
+		return err, !err.written
+	}
+	return nil, false
+}
+
+func (a *GitCodeAuthentication) auth(w http.ResponseWriter, r *http.Request) *AuthError {
+	a.matchedKey = 0
+	a.redelivery = false
+	a.recorded = false
 	if r == nil {
-		return errorNilRequest, false
+		return &AuthError{Code: AuthErrorNilRequest, StatusCode: http.StatusBadRequest,
+			Message: errorNilRequest.Error(), Err: errorNilRequest}
 	}
 
-	if r.Header.Get(headerUserAgent) != headerUserAgentValue {
-		return errors.New(headerUserAgentErrorMessage), true
+	if !a.cfg.userAgentAllowed(r.Header.Get(headerUserAgent)) {
+		return authFail(w, AuthErrorUserAgent, http.StatusBadRequest, headerUserAgentErrorMessage)
 	}
 
 	var err error
-	if a.payload, err = ReadPayload(w, r); err != nil {
-		return err, false
+	if a.payload, err = ReadPayloadLimit(w, r, a.cfg.maxBodySize()); err != nil {
+		code, status := AuthErrorReadBody, http.StatusBadRequest
+		if isMaxBytesError(err) {
+			code, status = AuthErrorBodyTooLarge, http.StatusRequestEntityTooLarge
+		}
+		return &AuthError{Code: code, StatusCode: status, Message: err.Error(), Err: err, written: w != nil}
 	}
 
 	// Header checks: It must be a POST with an event type and a signature.
 	if r.Method != http.MethodPost {
-		return handleErr(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed)), false
+		return authFail(w, AuthErrorMethod, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
 	}
 
-	if v := r.Header.Get(headerContentTypeName); !strings.HasPrefix(v, headerContentTypeJsonValue) {
-		return handleErr(w, http.StatusBadRequest, headerContentTypeErrorMessage), false
+	if !a.cfg.contentTypeAllowed(r.Header.Get(headerContentTypeName)) {
+		return authFail(w, AuthErrorContentType, http.StatusBadRequest, headerContentTypeErrorMessage)
 	}
 
 	if a.eventType = r.Header.Get(headerEventType); a.eventType == "" {
-		return handleErr(w, http.StatusBadRequest, headerEventErrorMessage), false
+		return authFail(w, AuthErrorMissingEvent, http.StatusBadRequest, headerEventErrorMessage)
 	}
 
 	token := r.Header.Get(headerEventToken)
+	if token == "" && a.legacyToken {
+		if plain := r.Header.Get(headerLegacyToken); plain != "" {
+			if a.matchedKey = matchPlainToken(plain, a.keys()); a.matchedKey == 0 {
+				return authFail(w, AuthErrorInvalidSignature, http.StatusUnauthorized, headerInvalidTokenErrorMessage)
+			}
+			return a.checkReplay(w, r)
+		}
+	}
 	if token == "" {
-		return handleErr(w, http.StatusUnauthorized, headerEmptyTokenErrorMessage), false
+		return authFail(w, AuthErrorMissingSignature, http.StatusUnauthorized, headerEmptyTokenErrorMessage)
 	}
 
-	// Validate the payload with our HMAC secret.
-	if !signSuccess(token, a.signKey, a.payload) {
-		return handleErr(w, http.StatusUnauthorized, headerInvalidTokenErrorMessage), false
+	// Validate the payload with our HMAC secrets.
+	if a.matchedKey = matchSignature(token, a.keys(), a.payload); a.matchedKey == 0 {
+		return authFail(w, AuthErrorInvalidSignature, http.StatusUnauthorized, headerInvalidTokenErrorMessage)
 	}
 
+	return a.checkReplay(w, r)
+}
+
+// checkReplay runs the timestamp and delivery ID checks on a request whose signature is valid.
+func (a *GitCodeAuthentication) checkReplay(w http.ResponseWriter, r *http.Request) *AuthError {
 	a.eventGUID = r.Header.Get(headerEventGUID)
 
-	return nil, false
+	if a.maxClockSkew > 0 {
+		ms, err := strconv.ParseInt(r.Header.Get(headerEventTimestamp), 10, 64)
+		if err != nil {
+			return authFail(w, AuthErrorTimestamp, http.StatusBadRequest, headerTimestampErrorMessage)
+		}
+		skew := time.Since(time.UnixMilli(ms))
+		if skew > a.maxClockSkew || skew < -a.maxClockSkew {
+			return authFail(w, AuthErrorClockSkew, http.StatusUnauthorized, clockSkewErrorMessage)
+		}
+	}
+
+	if a.deliveryStore == nil || a.eventGUID == "" {
+		return nil
+	}
+
+	seen, err := a.deliveryStore.Seen(a.eventGUID)
+	if err != nil {
+		ae := authFail(w, AuthErrorDeliveryStore, http.StatusInternalServerError, deliveryStoreErrorMessage)
+		if ae.Err == nil {
+			ae.Err = err
+		}
+		return ae
+	}
+	if seen && a.duplicateMode == DuplicateDeliveryReject {
+		return authFail(w, AuthErrorDuplicateDelivery, http.StatusConflict, duplicateDeliveryErrorMessage)
+	}
+	a.redelivery, a.recorded = seen, !seen
+	return nil
+}
+
+// authFail writes the error response, if w is non-nil, and returns the matching AuthError.
+func authFail(w http.ResponseWriter, code AuthErrorCode, status int, msg string) *AuthError {
+	ae := &AuthError{Code: code, StatusCode: status, Message: msg}
+	if err := handleErr(w, status, msg); errors.Is(err, errorNilResponse) {
+		ae.Err = errorNilResponse
+	} else {
+		ae.written = true
+	}
+	return ae
 }
 
+func isMaxBytesError(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// DefaultMaxPayloadSize is the body size limit applied when none is configured.
+const DefaultMaxPayloadSize int64 = 25 << 20
+
+// payloadLimit maps a configured limit to the one to enforce: 0 selects the default
+// and a negative value disables the limit.
+func payloadLimit(limit int64) int64 {
+	switch {
+	case limit == 0:
+		return DefaultMaxPayloadSize
+	case limit < 0:
+		return 0
+	default:
+		return limit
+	}
+}
+
+// ReadPayloadLimit is ReadPayload refusing bodies larger than limit bytes with 413
+// Request Entity Too Large. The error is then an *http.MaxBytesError. A limit <= 0 disables the check.
+// As with ReadPayload, no response is written when w is nil.
+func ReadPayloadLimit(w http.ResponseWriter, r *http.Request, limit int64) (*bytes.Buffer, error) {
+	if r.Body == nil || r.Body == http.NoBody || limit <= 0 {
+		return ReadPayload(w, r)
+	}
+
+	if r.ContentLength > limit {
+		_ = r.Body.Close()
+		_ = handleErr(w, http.StatusRequestEntityTooLarge, bodyTooLargeErrorMessage)
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return ReadPayload(w, r)
+}
+
+// ReadPayload reads the whole request body, writing a 400 or 413 response to w, if non-nil,
+// when that fails. Afterwards r.Body is replaced by a reader over
+// the same bytes, so handlers called later, or GitCodeAccessor.Parse, can read it again.
 func ReadPayload(w http.ResponseWriter, r *http.Request) (*bytes.Buffer, error) {
 	if r.Body == nil {
 		return nil, nil
 	}
 
+	body := r.Body
 	defer func() {
-		_ = r.Body.Close()
+		_ = body.Close()
 	}()
 	var payload bytes.Buffer
-	if r.Body != http.NoBody {
-		if _, err := io.Copy(&payload, r.Body); err != nil {
-			http.Error(w, bodyReadErrorMessage, http.StatusBadRequest)
+	if body != http.NoBody {
+		if _, err := io.Copy(&payload, body); err != nil {
+			if isMaxBytesError(err) {
+				_ = handleErr(w, http.StatusRequestEntityTooLarge, bodyTooLargeErrorMessage)
+			} else {
+				_ = handleErr(w, http.StatusBadRequest, bodyReadErrorMessage)
+			}
 			return nil, err
 		}
 	}
+
+	data := bytes.Clone(payload.Bytes())
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
 	return &payload, nil
 }
 
@@ -154,13 +403,45 @@ func handleErr(w http.ResponseWriter, errCode int, errMsg string) error {
 }
 
 func signSuccess(token, signKey string, payload *bytes.Buffer) bool {
-	if !strings.HasPrefix(token, "sha256=") {
-		return false
+	return matchSignature(token, []string{signKey}, payload) != 0
+}
+
+// matchSignature returns index+1 of the key whose HMAC-SHA256 of payload equals the
+// "sha256=<hex>" token, or 0 if none does. Digests are compared in constant time.
+// payloadBytes returns the content of payload, which is nil for requests without a body.
+func payloadBytes(payload *bytes.Buffer) []byte {
+	if payload == nil {
+		return nil
+	}
+	return payload.Bytes()
+}
+
+func matchSignature(token string, keys []string, payload *bytes.Buffer) int {
+	if !strings.HasPrefix(token, signaturePrefix) {
+		return 0
+	}
+	got, err := hex.DecodeString(token[len(signaturePrefix):])
+	if err != nil {
+		return 0
 	}
 
-	mac := hmac.New(sha256.New, []byte(signKey))
-	mac.Write(payload.Bytes())
+	data := payloadBytes(payload)
+	for i, key := range keys {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(data)
+		if hmac.Equal(got, mac.Sum(nil)) {
+			return i + 1
+		}
+	}
+	return 0
+}
 
-	expected := hex.EncodeToString(mac.Sum(nil))
-	return expected == token[7:]
+// matchPlainToken returns index+1 of the key equal to token, or 0 if none is.
+func matchPlainToken(token string, keys []string) int {
+	for i, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return i + 1
+		}
+	}
+	return 0
 }
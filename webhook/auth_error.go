@@ -0,0 +1,104 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import "strings"
+
+// AuthErrorCode tells why Auth rejected a request.
+type AuthErrorCode string
+
+const (
+	AuthErrorNilRequest        AuthErrorCode = "nil_request"
+	AuthErrorUserAgent         AuthErrorCode = "invalid_user_agent"
+	AuthErrorReadBody          AuthErrorCode = "read_body"
+	AuthErrorBodyTooLarge      AuthErrorCode = "body_too_large"
+	AuthErrorMethod            AuthErrorCode = "method_not_allowed"
+	AuthErrorContentType       AuthErrorCode = "invalid_content_type"
+	AuthErrorMissingEvent      AuthErrorCode = "missing_event"
+	AuthErrorMissingSignature  AuthErrorCode = "missing_signature"
+	AuthErrorInvalidSignature  AuthErrorCode = "invalid_signature"
+	AuthErrorTimestamp         AuthErrorCode = "invalid_timestamp"
+	AuthErrorClockSkew         AuthErrorCode = "clock_skew"
+	AuthErrorDuplicateDelivery AuthErrorCode = "duplicate_delivery"
+	AuthErrorDeliveryStore     AuthErrorCode = "delivery_store"
+)
+
+// AuthError is the error returned by GitCodeAuthentication.Auth.
+// Error returns the message that was, or should be, written as the response body.
+type AuthError struct {
+	Code       AuthErrorCode
+	StatusCode int
+	Message    string
+	// Err is the underlying error, such as the one returned while reading the body.
+	Err error
+
+	written bool
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// AuthConfig relaxes or tightens the request checks of GitCodeAuthentication.Auth.
+// The zero value keeps the defaults GitCode deliveries are checked against.
+type AuthConfig struct {
+	// UserAgents lists the accepted User-Agent values, git-gitcode-hook when empty.
+	UserAgents []string
+	// SkipUserAgentCheck accepts any User-Agent, for proxies that rewrite it.
+	SkipUserAgentCheck bool
+	// ContentTypes lists the accepted Content-Type prefixes, application/json when empty.
+	ContentTypes []string
+	// MaxBodySize rejects bodies larger than this many bytes with 413.
+	// 0 selects DefaultMaxPayloadSize and a negative value disables the limit.
+	MaxBodySize int64
+}
+
+func (c *AuthConfig) maxBodySize() int64 {
+	return payloadLimit(c.MaxBodySize)
+}
+
+func (c *AuthConfig) userAgentAllowed(ua string) bool {
+	if c.SkipUserAgentCheck {
+		return true
+	}
+	if len(c.UserAgents) == 0 {
+		return ua == headerUserAgentValue
+	}
+	for _, v := range c.UserAgents {
+		if ua == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AuthConfig) contentTypeAllowed(ct string) bool {
+	if len(c.ContentTypes) == 0 {
+		return hasPrefixFold(ct, headerContentTypeJsonValue)
+	}
+	for _, v := range c.ContentTypes {
+		if hasPrefixFold(ct, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -24,6 +24,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -255,8 +256,16 @@ func TestGitCodeAuthenticationAuthByMock(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/case8", nil)
 	req.Header.Set(headerUserAgent, headerUserAgentValue)
 
-	err, _ := a.Auth(httptest.NewRecorder(), req)
-	assert.Equal(t, err, e)
+	err, unwritten := a.Auth(httptest.NewRecorder(), req)
+	assert.True(t, errors.Is(err, e))
+	assert.Equal(t, "fad", err.Error())
+	assert.Equal(t, false, unwritten)
+
+	var ae *AuthError
+	if assert.True(t, errors.As(err, &ae)) {
+		assert.Equal(t, AuthErrorReadBody, ae.Code)
+		assert.Equal(t, http.StatusBadRequest, ae.StatusCode)
+	}
 }
 
 func TestGitCodeAuthenticationSetSignKey(t *testing.T) {
@@ -568,3 +577,199 @@ func TestSignSuccess(t *testing.T) {
 		})
 	}
 }
+
+func TestGitCodeAuthenticationSetSignKeys(t *testing.T) {
+	a := GitCodeAuthentication{}
+	assert.Equal(t, errorNilToken, a.SetSignKeys())
+	assert.Equal(t, errorNilToken, a.SetSignKeys([]byte("old"), nil))
+
+	assert.Equal(t, nil, a.SetSignKeys([]byte("old"), []byte("new")))
+	assert.Equal(t, []string{"old", "new"}, a.keys())
+
+	assert.Equal(t, nil, a.SetSignKey([]byte("only")))
+	assert.Equal(t, []string{"only"}, a.keys())
+}
+
+func TestGitCodeAuthenticationKeyRotation(t *testing.T) {
+	newRequest := func(header, token string) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rotation", bytes.NewBufferString(payloadData))
+		req.Header.Set(headerUserAgent, headerUserAgentValue)
+		req.Header.Set(headerContentTypeName, headerContentTypeJsonValue)
+		req.Header.Set(headerEventType, noteEvent)
+		req.Header.Set(header, token)
+		return req
+	}
+
+	a := GitCodeAuthentication{}
+	_ = a.SetSignKeys([]byte("5678"), []byte("1234"))
+	assert.Equal(t, -1, a.GetMatchedKeyIndex())
+
+	err, _ := a.Auth(httptest.NewRecorder(), newRequest(headerEventToken, "sha256=f585860d0ca237e0550da0e166370b9c372e8aeb2e639b0ac9884cd52681c576"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, a.GetMatchedKeyIndex())
+
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest(headerEventToken, "sha256=zz"))
+	assert.Equal(t, headerInvalidTokenErrorMessage, err.Error())
+	assert.Equal(t, -1, a.GetMatchedKeyIndex())
+
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest(headerLegacyToken, "1234"))
+	assert.Equal(t, headerEmptyTokenErrorMessage, err.Error())
+
+	a.SetLegacyToken(true)
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest(headerLegacyToken, "1234"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, a.GetMatchedKeyIndex())
+
+	w := httptest.NewRecorder()
+	err, _ = a.Auth(w, newRequest(headerLegacyToken, "12345"))
+	assert.Equal(t, headerInvalidTokenErrorMessage, err.Error())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, -1, a.GetMatchedKeyIndex())
+}
+
+func TestGitCodeAuthenticationAuthConfig(t *testing.T) {
+	newRequest := func(ua, contentType, body string) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/config", strings.NewReader(body))
+		req.Header.Set(headerUserAgent, ua)
+		req.Header.Set(headerContentTypeName, contentType)
+		req.Header.Set(headerEventType, noteEvent)
+		req.Header.Set(headerEventToken, Sign([]byte("1234"), []byte(body)))
+		return req
+	}
+	code := func(err error) AuthErrorCode {
+		var ae *AuthError
+		if errors.As(err, &ae) {
+			return ae.Code
+		}
+		return ""
+	}
+
+	a := GitCodeAuthentication{signKey: "1234"}
+	w := httptest.NewRecorder()
+	err, unwritten := a.Auth(w, newRequest("proxy/1.0", headerContentTypeJsonValue, "{}"))
+	assert.Equal(t, headerUserAgentErrorMessage, err.Error())
+	assert.Equal(t, AuthErrorUserAgent, code(err))
+	assert.Equal(t, false, unwritten)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	err, unwritten = a.Auth(nil, newRequest("proxy/1.0", headerContentTypeJsonValue, "{}"))
+	assert.Equal(t, true, unwritten)
+	assert.True(t, errors.Is(err, errorNilResponse))
+
+	err, unwritten = a.Auth(w, nil)
+	assert.Equal(t, true, unwritten)
+	assert.Equal(t, AuthErrorNilRequest, code(err))
+
+	a.SetConfig(AuthConfig{UserAgents: []string{"proxy/1.0", headerUserAgentValue}})
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("proxy/1.0", headerContentTypeJsonValue, "{}"))
+	assert.Equal(t, nil, err)
+
+	a.SetConfig(AuthConfig{SkipUserAgentCheck: true, ContentTypes: []string{"application/json", "text/plain"}})
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("", "text/plain; charset=utf-8", "{}"))
+	assert.Equal(t, nil, err)
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("", "application/x-www-form-urlencoded", "{}"))
+	assert.Equal(t, AuthErrorContentType, code(err))
+
+	a.SetConfig(AuthConfig{MaxBodySize: 4})
+	w = httptest.NewRecorder()
+	err, unwritten = a.Auth(w, newRequest(headerUserAgentValue, headerContentTypeJsonValue, "{\"a\":1}"))
+	assert.Equal(t, AuthErrorBodyTooLarge, code(err))
+	assert.Equal(t, false, unwritten)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// a nil writer leaves the response to the caller
+	err, unwritten = a.Auth(nil, newRequest(headerUserAgentValue, headerContentTypeJsonValue, "{\"a\":1}"))
+	assert.Equal(t, AuthErrorBodyTooLarge, code(err))
+	assert.Equal(t, true, unwritten)
+	var mbe *http.MaxBytesError
+	assert.True(t, errors.As(err, &mbe))
+
+	req := newRequest(headerUserAgentValue, headerContentTypeJsonValue, "{\"a\":1}")
+	req.ContentLength = -1
+	err, unwritten = a.Auth(nil, req)
+	assert.Equal(t, AuthErrorBodyTooLarge, code(err))
+	assert.Equal(t, true, unwritten)
+
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest(headerUserAgentValue, headerContentTypeJsonValue, "{}"))
+	assert.Equal(t, nil, err)
+
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest(headerUserAgentValue, headerContentTypeJsonValue, "[]"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, int(a.config().cfg.MaxBodySize))
+}
+
+func TestReadPayloadLimit(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/limit", strings.NewReader(payloadData))
+	w := httptest.NewRecorder()
+	payload, err := ReadPayloadLimit(w, req, 4)
+	var mbe *http.MaxBytesError
+	assert.True(t, errors.As(err, &mbe))
+	assert.Equal(t, int64(4), mbe.Limit)
+	assert.Equal(t, (*bytes.Buffer)(nil), payload)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, bodyTooLargeErrorMessage+"\n", w.Body.String())
+
+	// a streamed body has no Content-Length, so the limit is hit while reading
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/limit", io.MultiReader(strings.NewReader(payloadData)))
+	assert.Equal(t, int64(0), req.ContentLength)
+	w = httptest.NewRecorder()
+	_, err = ReadPayloadLimit(w, req, 4)
+	assert.True(t, errors.As(err, &mbe))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/limit", strings.NewReader(payloadData))
+	payload, err = ReadPayloadLimit(httptest.NewRecorder(), req, int64(len(payloadData)))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, payloadData, payload.String())
+
+	again, err := ReadPayload(httptest.NewRecorder(), req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, payloadData, again.String())
+	body, _ := req.GetBody()
+	data, _ := io.ReadAll(body)
+	assert.Equal(t, payloadData, string(data))
+
+	payload.Reset()
+	payload.WriteString("overwritten")
+	again, _ = ReadPayload(httptest.NewRecorder(), req)
+	assert.Equal(t, payloadData, again.String())
+
+	assert.Equal(t, DefaultMaxPayloadSize, payloadLimit(0))
+	assert.Equal(t, int64(0), payloadLimit(-1))
+}
+
+func TestAuthThenParse(t *testing.T) {
+	data := readWebHookTestdata(t, webhookTestDataDir+"issues_create.json", nil)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/reread", bytes.NewReader(data))
+	req.Header.Set(headerUserAgent, headerUserAgentValue)
+	req.Header.Set(headerContentTypeName, headerContentTypeJsonValue)
+	req.Header.Set(headerEventType, issueEvent)
+	req.Header.Set(headerEventToken, Sign([]byte("1234"), data))
+
+	a := GitCodeAuthentication{signKey: "1234"}
+	err, _ := a.Auth(httptest.NewRecorder(), req)
+	assert.Equal(t, nil, err)
+
+	accessor := new(GitCodeAccessor)
+	event, payload, _, _, err := accessor.Parse(httptest.NewRecorder(), req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, payload.Bytes())
+	assert.Equal(t, "4", *event.GetNumber())
+
+	accessor.SetMaxPayloadSize(16)
+	w := httptest.NewRecorder()
+	_, _, _, _, err = accessor.Parse(w, req)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestGitCodeAuthenticationClone(t *testing.T) {
+	a := GitCodeAuthentication{signKey: "1234", eventType: noteEvent, payload: bytes.NewBufferString("{}")}
+	a.SetMaxClockSkew(time.Minute)
+
+	c := a.Clone()
+	assert.Equal(t, "1234", c.signKey)
+	assert.Equal(t, time.Minute, c.maxClockSkew)
+	assert.Equal(t, "", c.GetEventType())
+	assert.Equal(t, (*bytes.Buffer)(nil), c.GetPayload())
+}
@@ -0,0 +1,538 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package cache keeps issues and pull requests in a local cache that is loaded from the
+// OpenAPI on first access and updated by webhook events, so bots do not have to call
+// GetPullRequest and GetLabelsOfPullRequest for every event.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const (
+	defaultTTL         = 10 * time.Minute
+	defaultLoadTimeout = 30 * time.Second
+)
+
+var errorNoLoader = errors.New("cache has no API client to load the entry")
+
+// Kind tells issues and pull requests apart, they are numbered independently.
+type Kind string
+
+const (
+	KindIssue       Kind = "issue"
+	KindPullRequest Kind = "pull_request"
+)
+
+// Key identifies an issue or pull request.
+type Key struct {
+	Kind   Kind
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s/%s %s#%d", k.Owner, k.Repo, k.Kind, k.Number)
+}
+
+// Comment is a comment received by webhook.
+type Comment struct {
+	// ID is the ID of the note, not of its discussion, empty if the payload has none.
+	ID        string
+	Body      string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Entry is a cached issue or pull request. Exactly one of PullRequest and Issue is set.
+type Entry struct {
+	Key         Key
+	PullRequest *openapi.PullRequest
+	Issue       *openapi.Issue
+	Labels      []*openapi.Label
+	// Comments holds the comments received by webhook since the entry was first loaded,
+	// ordered by creation time. The API is not asked for them, they are kept across reloads.
+	Comments []*Comment
+	// FetchedAt is when the entry was last loaded from the API.
+	FetchedAt time.Time
+	// UpdatedAt is the time of the last change of state, title or labels, from the API or a
+	// webhook event. Comments do not change it.
+	UpdatedAt time.Time
+}
+
+// LabelNames returns the names of the labels.
+func (e *Entry) LabelNames() []string {
+	names := make([]string, 0, len(e.Labels))
+	for _, l := range e.Labels {
+		if l != nil {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// HasLabel reports whether the entry carries the label name.
+func (e *Entry) HasLabel(name string) bool {
+	return slices.Contains(e.LabelNames(), name)
+}
+
+// State returns the state of the issue or pull request, such as "open" or "closed".
+func (e *Entry) State() string {
+	switch {
+	case e.PullRequest != nil && e.PullRequest.State != nil:
+		return *e.PullRequest.State
+	case e.Issue != nil && e.Issue.State != nil:
+		return *e.Issue.State
+	}
+	return ""
+}
+
+func (e *Entry) clone() *Entry {
+	c := *e
+	if e.PullRequest != nil {
+		pr := *e.PullRequest
+		c.PullRequest = &pr
+	}
+	if e.Issue != nil {
+		issue := *e.Issue
+		c.Issue = &issue
+	}
+	c.Labels = slices.Clone(e.Labels)
+	c.Comments = slices.Clone(e.Comments)
+	return &c
+}
+
+// PullRequestGetter is implemented by *openapi.PullRequestsService.
+type PullRequestGetter interface {
+	GetPullRequest(ctx context.Context, owner, repo, number string) (*openapi.PullRequest, bool, error)
+	GetLabelsOfPullRequest(ctx context.Context, owner, repo, number string) ([]*openapi.Label, bool, error)
+}
+
+// IssueGetter is implemented by *openapi.IssuesService.
+type IssueGetter interface {
+	GetIssue(ctx context.Context, owner, repo, number string) (*openapi.Issue, bool, error)
+}
+
+var (
+	_ PullRequestGetter = (*openapi.PullRequestsService)(nil)
+	_ IssueGetter       = (*openapi.IssuesService)(nil)
+)
+
+// Options configures a Cache. Zero values select the defaults.
+type Options struct {
+	// Store keeps the entries, a MemoryStore by default.
+	Store Store
+	// TTL is how long an entry is used before it is loaded again, 10 minutes by default.
+	// Webhook events update entries but do not extend their TTL, so events that GitCode
+	// failed to deliver are corrected eventually.
+	TTL time.Duration
+	// LoadTimeout bounds loading an entry from the API, 30 seconds by default. A load is
+	// shared by the concurrent callers for the entry, so it does not end when the caller
+	// that started it gives up.
+	LoadTimeout time.Duration
+}
+
+type call struct {
+	done    chan struct{}
+	entry   *Entry
+	err     error
+	pending []webhook.Event // passed to Apply while the entry was loading
+}
+
+// Cache loads issues and pull requests on first access and keeps them up to date with Apply.
+type Cache struct {
+	prs    PullRequestGetter
+	issues IssueGetter
+	store  Store
+	ttl    time.Duration
+	// loadTimeout bounds a load started by loadOnce.
+	loadTimeout time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	loading map[Key]*call
+}
+
+// New creates a Cache loading pull requests with prs and issues with issues.
+// Either may be nil when the cache is not used for that kind.
+func New(prs PullRequestGetter, issues IssueGetter, opts Options) *Cache {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = defaultLoadTimeout
+	}
+
+	return &Cache{
+		prs:         prs,
+		issues:      issues,
+		store:       opts.Store,
+		ttl:         opts.TTL,
+		loadTimeout: opts.LoadTimeout,
+		now:         time.Now,
+		loading:     map[Key]*call{},
+	}
+}
+
+// PullRequest returns the cached pull request, loading it when it is missing or expired.
+func (c *Cache) PullRequest(ctx context.Context, owner, repo string, number int) (*Entry, error) {
+	return c.Get(ctx, Key{Kind: KindPullRequest, Owner: owner, Repo: repo, Number: number})
+}
+
+// Issue returns the cached issue, loading it when it is missing or expired.
+func (c *Cache) Issue(ctx context.Context, owner, repo string, number int) (*Entry, error) {
+	return c.Get(ctx, Key{Kind: KindIssue, Owner: owner, Repo: repo, Number: number})
+}
+
+// Get returns the entry of key, loading it when it is missing or older than the TTL.
+// Concurrent calls for the same key share one load. When revalidating an expired entry
+// fails, the stale entry is returned together with the error.
+// The returned entry must not be modified.
+func (c *Cache) Get(ctx context.Context, key Key) (*Entry, error) {
+	cached, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok && c.now().Sub(cached.FetchedAt) < c.ttl {
+		return cached, nil
+	}
+
+	entry, err := c.loadOnce(ctx, key)
+	if err != nil {
+		if ok {
+			return cached, err
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Invalidate drops the entry of key, it is loaded again on the next access.
+func (c *Cache) Invalidate(ctx context.Context, key Key) error {
+	return c.store.Delete(ctx, key)
+}
+
+// loadOnce waits until the entry of key is loaded or ctx is done. The load is shared with
+// the concurrent callers for key and runs with a context that is not cancelled with ctx.
+func (c *Cache) loadOnce(ctx context.Context, key Key) (*Entry, error) {
+	c.mu.Lock()
+	cl, ok := c.loading[key]
+	if !ok {
+		cl = &call{done: make(chan struct{})}
+		c.loading[key] = cl
+		go c.loadCall(context.WithoutCancel(ctx), key, cl)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.entry, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Cache) loadCall(ctx context.Context, key Key, cl *call) {
+	ctx, cancel := context.WithTimeout(ctx, c.loadTimeout)
+	defer cancel()
+
+	entry, err := c.load(ctx, key)
+
+	// The API may have answered before or after the events applied during the load,
+	// so they are applied again, skipping those older than the loaded entry. Comments
+	// are not loaded, those of the entry being replaced are kept.
+	c.mu.Lock()
+	if err == nil {
+		err = c.keepComments(ctx, entry)
+	}
+	if err == nil {
+		for _, e := range cl.pending {
+			if updated, ok := c.apply(entry, e); ok {
+				entry = updated
+			}
+		}
+		err = c.store.Put(ctx, entry)
+	}
+	cl.entry, cl.err = entry, err
+	delete(c.loading, key)
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+// keepComments copies the comments of the stored entry replaced by entry.
+func (c *Cache) keepComments(ctx context.Context, entry *Entry) error {
+	stale, ok, err := c.store.Get(ctx, entry.Key)
+	if err != nil || !ok {
+		return err
+	}
+	entry.Comments = slices.Clone(stale.Comments)
+	return nil
+}
+
+func (c *Cache) load(ctx context.Context, key Key) (*Entry, error) {
+	number := strconv.Itoa(key.Number)
+	entry := &Entry{Key: key, FetchedAt: c.now()}
+
+	switch key.Kind {
+	case KindPullRequest:
+		if c.prs == nil {
+			return nil, errorNoLoader
+		}
+		pr, _, err := c.prs.GetPullRequest(ctx, key.Owner, key.Repo, number)
+		if err != nil {
+			return nil, err
+		}
+		labels, _, err := c.prs.GetLabelsOfPullRequest(ctx, key.Owner, key.Repo, number)
+		if err != nil {
+			return nil, err
+		}
+		entry.PullRequest, entry.Labels = pr, labels
+		entry.UpdatedAt = timeOf(pr.UpdatedAt)
+	case KindIssue:
+		if c.issues == nil {
+			return nil, errorNoLoader
+		}
+		issue, _, err := c.issues.GetIssue(ctx, key.Owner, key.Repo, number)
+		if err != nil {
+			return nil, err
+		}
+		entry.Issue, entry.Labels = issue, issue.Labels
+		entry.UpdatedAt = timeOf(issue.UpdatedAt)
+	default:
+		return nil, fmt.Errorf("unknown cache kind %q", key.Kind)
+	}
+	if entry.UpdatedAt.IsZero() {
+		entry.UpdatedAt = entry.FetchedAt
+	}
+	return entry, nil
+}
+
+// Handler returns a webhook.EventHandler applying events to c, for webhook.Dispatcher.OnMatch.
+func (c *Cache) Handler() webhook.EventHandler {
+	return c.Apply
+}
+
+// Apply updates the cached entry an event refers to: labels and state of issues and pull
+// requests, and comments. Events for entries that are not cached are ignored, they are
+// loaded on first access, and so are issue and pull request events older than the last
+// change of the entry. Comments are added whenever they arrive, unless already there.
+// Events for entries being loaded are also applied to the loaded entry before it is stored.
+func (c *Cache) Apply(ctx context.Context, e webhook.Event) error {
+	key, ok := keyOf(webhook.NewEnvelope(e))
+	if !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cl, ok := c.loading[key]; ok {
+		cl.pending = append(cl.pending, e)
+	}
+
+	cached, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+	if entry, ok := c.apply(cached, e); ok {
+		return c.store.Put(ctx, entry)
+	}
+	return nil
+}
+
+// apply returns a copy of cached updated by e, or false if e changes nothing: an issue or
+// pull request event older than cached, or a comment cached already.
+func (c *Cache) apply(cached *Entry, e webhook.Event) (*Entry, bool) {
+	// comments do not depend on each other or on the state, so their order of delivery
+	// does not matter
+	if note, ok := e.(*webhook.NoteEvent); ok {
+		return applyNote(cached, note)
+	}
+
+	at := webhook.NewEnvelope(e).UpdatedAt
+	if at.IsZero() {
+		at = c.now()
+	} else if at.Before(cached.UpdatedAt) {
+		return nil, false
+	}
+
+	entry := cached.clone()
+	switch v := e.(type) {
+	case *webhook.PullRequestEvent:
+		applyPullRequest(entry, v)
+	case *webhook.IssueEvent:
+		applyIssue(entry, v)
+	}
+	entry.UpdatedAt = at
+	return entry, true
+}
+
+func keyOf(env *webhook.Envelope) (Key, bool) {
+	if env == nil || env.Number <= 0 {
+		return Key{}, false
+	}
+
+	key := Key{Owner: env.Org, Repo: env.Repo, Number: env.Number}
+	switch env.Kind {
+	case webhook.EventKindPullRequest, webhook.EventKindIssue, webhook.EventKindNote:
+	default:
+		return Key{}, false
+	}
+	switch env.Target {
+	case webhook.TargetPullRequest:
+		key.Kind = KindPullRequest
+	case webhook.TargetIssue:
+		key.Kind = KindIssue
+	default:
+		return Key{}, false
+	}
+	return key, true
+}
+
+// state maps webhook states and actions to the states returned by the API.
+func state(action, current *string) *string {
+	var s string
+	switch deref(action) {
+	case "open", "reopen":
+		s = "open"
+	case "close":
+		s = "closed"
+	case "merge":
+		s = "merged"
+	default:
+		switch deref(current) {
+		case "":
+			return nil
+		case "opened", "reopened":
+			s = "open"
+		default:
+			s = *current
+		}
+	}
+	return &s
+}
+
+func applyPullRequest(entry *Entry, e *webhook.PullRequestEvent) {
+	pr := entry.PullRequest
+	if pr == nil {
+		return
+	}
+	if a := e.Attributes; a != nil {
+		if s := state(a.Action, a.State); s != nil {
+			pr.State = s
+			if *s == "merged" {
+				merged := true
+				pr.Merged = &merged
+			}
+		}
+		if a.Title != nil {
+			pr.Title = a.Title
+		}
+		if a.Draft != nil {
+			pr.Draft = a.Draft
+		}
+	}
+	if sha := e.GetHeadSHA(); sha != nil {
+		head := openapi.PullRequestBranch{}
+		if pr.Head != nil {
+			head = *pr.Head
+		}
+		head.SHA = sha
+		pr.Head = &head
+	}
+	if e.Labels != nil {
+		entry.Labels = slices.Clone(e.Labels)
+	}
+}
+
+func applyIssue(entry *Entry, e *webhook.IssueEvent) {
+	issue := entry.Issue
+	if issue == nil {
+		return
+	}
+	if a := e.Attributes; a != nil {
+		if s := state(a.Action, a.State); s != nil {
+			issue.State = s
+		}
+		if a.Title != nil {
+			issue.Title = a.Title
+		}
+	}
+	if e.Labels != nil {
+		entry.Labels = slices.Clone(e.Labels)
+	}
+}
+
+// applyNote returns a copy of cached with the comment inserted in creation order, or false
+// if it is already there. Comments are told apart by the ID of the note, GetCommentID
+// returns the ID of its discussion, which replies share.
+func applyNote(cached *Entry, e *webhook.NoteEvent) (*Entry, bool) {
+	comment := &Comment{
+		Body:   deref(e.GetComment()),
+		Author: deref(e.GetCommenter()),
+	}
+	if a := e.Attributes; a != nil {
+		if a.ID != nil {
+			comment.ID = a.ID.String()
+		}
+		comment.CreatedAt = timeOf(a.CreateTime)
+	}
+	if slices.ContainsFunc(cached.Comments, comment.same) {
+		return nil, false
+	}
+
+	entry := cached.clone()
+	i := len(entry.Comments)
+	for i > 0 && comment.CreatedAt.Before(entry.Comments[i-1].CreatedAt) {
+		i--
+	}
+	entry.Comments = slices.Insert(entry.Comments, i, comment)
+	return entry, true
+}
+
+// same reports whether c and other are the same note, comparing the content of notes
+// without ID.
+func (c *Comment) same(other *Comment) bool {
+	if c.ID != "" || other.ID != "" {
+		return c.ID == other.ID
+	}
+	return c.Body == other.Body && c.Author == other.Author && c.CreatedAt.Equal(other.CreatedAt)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timeOf(t *openapi.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Time(*t)
+}
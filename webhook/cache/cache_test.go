@@ -0,0 +1,348 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/webhooktest"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type fakeAPI struct {
+	prCalls    atomic.Int32
+	issueCalls atomic.Int32
+	err        error
+	block      chan struct{}
+}
+
+func (f *fakeAPI) GetPullRequest(_ context.Context, owner, repo, number string) (*openapi.PullRequest, bool, error) {
+	f.prCalls.Add(1)
+	if f.block != nil {
+		<-f.block
+	}
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return &openapi.PullRequest{
+		Number: ptr(int64(4)),
+		State:  ptr("open"),
+		Title:  ptr(owner + "/" + repo + "#" + number),
+		Head:   &openapi.PullRequestBranch{Ref: ptr("dev"), SHA: ptr("a1")},
+	}, true, nil
+}
+
+func (f *fakeAPI) GetLabelsOfPullRequest(_ context.Context, _, _, _ string) ([]*openapi.Label, bool, error) {
+	return []*openapi.Label{{Name: "lgtm"}}, true, nil
+}
+
+func (f *fakeAPI) GetIssue(_ context.Context, _, _, number string) (*openapi.Issue, bool, error) {
+	f.issueCalls.Add(1)
+	return &openapi.Issue{Number: ptr(number), State: ptr("open"), Labels: []*openapi.Label{{Name: "bug"}}}, true, nil
+}
+
+func newTestCache(api *fakeAPI, now *time.Time) *Cache {
+	c := New(api, api, Options{TTL: time.Minute})
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestCacheGet(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	api := new(fakeAPI)
+	c := newTestCache(api, &now)
+
+	e, err := c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "org/repo#4", *e.PullRequest.Title)
+	assert.Equal(t, []string{"lgtm"}, e.LabelNames())
+	assert.Equal(t, now, e.UpdatedAt)
+
+	_, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, int32(1), api.prCalls.Load())
+
+	// expired entries are loaded again
+	now = now.Add(time.Minute)
+	_, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, int32(2), api.prCalls.Load())
+
+	// a failed revalidation returns the stale entry
+	now = now.Add(time.Minute)
+	api.err = errors.New("unavailable")
+	e, err = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, api.err, err)
+	assert.Equal(t, "open", e.State())
+
+	_, err = c.PullRequest(ctx, "org", "repo", 5)
+	assert.Equal(t, api.err, err)
+
+	e, err = c.Issue(ctx, "org", "repo", 4)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, e.HasLabel("bug"))
+	assert.Equal(t, int32(1), api.issueCalls.Load())
+
+	assert.Equal(t, nil, c.Invalidate(ctx, Key{Kind: KindIssue, Owner: "org", Repo: "repo", Number: 4}))
+	_, _ = c.Issue(ctx, "org", "repo", 4)
+	assert.Equal(t, int32(2), api.issueCalls.Load())
+
+	_, err = New(nil, nil, Options{}).Issue(ctx, "org", "repo", 1)
+	assert.Equal(t, errorNoLoader, err)
+}
+
+func TestCacheGetConcurrent(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	api := &fakeAPI{block: make(chan struct{})}
+	c := newTestCache(api, &now)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := c.PullRequest(context.Background(), "org", "repo", 4)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "open", e.State())
+		}()
+	}
+	for api.prCalls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(api.block)
+	wg.Wait()
+	assert.Equal(t, int32(1), api.prCalls.Load())
+}
+
+func TestCacheGetFirstCallerCancels(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	api := &fakeAPI{block: make(chan struct{})}
+	c := newTestCache(api, &now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.PullRequest(ctx, "org", "repo", 4)
+		first <- err
+	}()
+	for api.prCalls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error)
+	go func() {
+		_, err := c.PullRequest(context.Background(), "org", "repo", 4)
+		second <- err
+	}()
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-first)
+	close(api.block)
+	assert.Equal(t, nil, <-second)
+	assert.Equal(t, int32(1), api.prCalls.Load())
+
+	e, err := c.PullRequest(context.Background(), "org", "repo", 4)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "open", e.State())
+	assert.Equal(t, int32(1), api.prCalls.Load())
+}
+
+func TestCacheApply(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	api := new(fakeAPI)
+	c := newTestCache(api, &now)
+
+	// events for entries that are not cached are ignored
+	pr := webhooktest.PullRequestEvent("org", "repo", 4, "update", "alice", "main", "dev")
+	assert.Equal(t, nil, c.Apply(ctx, pr))
+	assert.Equal(t, 0, c.store.(*MemoryStore).Len())
+
+	_, _ = c.PullRequest(ctx, "org", "repo", 4)
+
+	now = now.Add(time.Second)
+	pr.Labels = []*openapi.Label{{Name: "lgtm"}, {Name: "approved"}}
+	pr.Attributes.Title = ptr("new title")
+	pr.Attributes.LastCommit = &webhook.PushCommit{ID: ptr("b2")}
+	assert.Equal(t, nil, c.Handler()(ctx, pr))
+
+	e, _ := c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, []string{"lgtm", "approved"}, e.LabelNames())
+	assert.Equal(t, "new title", *e.PullRequest.Title)
+	assert.Equal(t, "b2", *e.PullRequest.Head.SHA)
+	assert.Equal(t, "dev", *e.PullRequest.Head.Ref)
+	assert.Equal(t, now, e.UpdatedAt)
+	assert.Equal(t, int32(1), api.prCalls.Load())
+
+	merged := webhooktest.PullRequestEvent("org", "repo", 4, "merge", "alice", "main", "dev")
+	assert.Equal(t, nil, c.Apply(ctx, merged))
+	e, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, "merged", e.State())
+	assert.Equal(t, true, *e.PullRequest.Merged)
+	// labels are kept when the payload does not carry them
+	assert.Equal(t, 2, len(e.Labels))
+
+	// events older than the last change are ignored
+	old := webhooktest.PullRequestEvent("org", "repo", 4, "reopen", "alice", "main", "dev")
+	old.Attributes.UpdatedTime = ptr(openapi.Timestamp(now.Add(-time.Hour)))
+	assert.Equal(t, nil, c.Apply(ctx, old))
+	e, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, "merged", e.State())
+
+	note := webhooktest.PullRequestNoteEvent("org", "repo", 4, "bob", "/lgtm")
+	note.Attributes.ID = ptr(json.Number("1530794"))
+	assert.Equal(t, nil, c.Apply(ctx, note))
+	assert.Equal(t, nil, c.Apply(ctx, note))
+	// a reply shares the discussion ID but is a different note
+	reply := webhooktest.PullRequestNoteEvent("org", "repo", 4, "alice", "thanks")
+	reply.Attributes.ID = ptr(json.Number("1530795"))
+	assert.Equal(t, note.GetCommentID(), reply.GetCommentID())
+	assert.Equal(t, nil, c.Apply(ctx, reply))
+	e, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, 2, len(e.Comments))
+	assert.Equal(t, "bob", e.Comments[0].Author)
+	assert.Equal(t, "/lgtm", e.Comments[0].Body)
+	assert.Equal(t, "1530794", e.Comments[0].ID)
+	assert.Equal(t, "thanks", e.Comments[1].Body)
+
+	_, _ = c.Issue(ctx, "org", "repo", 2)
+	issue := webhooktest.IssueEvent("org", "repo", 2, "close", "alice")
+	issue.Labels = []*openapi.Label{}
+	assert.Equal(t, nil, c.Apply(ctx, issue))
+	assert.Equal(t, nil, c.Apply(ctx, webhooktest.IssueNoteEvent("org", "repo", 2, "carol", "ping")))
+	e, _ = c.Issue(ctx, "org", "repo", 2)
+	assert.Equal(t, "closed", e.State())
+	assert.Equal(t, 0, len(e.Labels))
+	assert.Equal(t, "carol", e.Comments[0].Author)
+
+	assert.Equal(t, nil, c.Apply(ctx, webhooktest.PushEvent("org", "repo", "refs/heads/main", "a", "b", "alice")))
+}
+
+func TestCacheApplyCommentOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	api := new(fakeAPI)
+	c := newTestCache(api, &now)
+	_, _ = c.PullRequest(ctx, "org", "repo", 4)
+
+	note := func(id, body string, at time.Time) *webhook.NoteEvent {
+		e := webhooktest.PullRequestNoteEvent("org", "repo", 4, "bob", body)
+		e.Attributes.ID = ptr(json.Number(id))
+		e.Attributes.CreateTime = ptr(openapi.Timestamp(at))
+		return e
+	}
+
+	labeled := webhooktest.PullRequestEvent("org", "repo", 4, "update", "alice", "main", "dev")
+	labeled.Attributes.UpdatedTime = ptr(openapi.Timestamp(now.Add(10 * time.Second)))
+	labeled.Labels = []*openapi.Label{{Name: "approved"}}
+	assert.Equal(t, nil, c.Apply(ctx, labeled))
+	assert.Equal(t, nil, c.Apply(ctx, note("3", "third", now.Add(11*time.Second))))
+	// created before the label change and the third comment, delivered after them
+	assert.Equal(t, nil, c.Apply(ctx, note("1", "first", now.Add(9*time.Second))))
+
+	e, _ := c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, 2, len(e.Comments))
+	assert.Equal(t, "first", e.Comments[0].Body)
+	assert.Equal(t, "third", e.Comments[1].Body)
+	assert.Equal(t, now.Add(10*time.Second), e.UpdatedAt)
+	assert.Equal(t, []string{"approved"}, e.LabelNames())
+
+	// notes without ID are told apart by their content
+	plain := webhooktest.PullRequestNoteEvent("org", "repo", 4, "carol", "ping")
+	assert.Equal(t, nil, c.Apply(ctx, plain))
+	assert.Equal(t, nil, c.Apply(ctx, plain))
+	e, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, 3, len(e.Comments))
+}
+
+func TestCacheCommentsSurviveReload(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	api := new(fakeAPI)
+	c := newTestCache(api, &now)
+	_, _ = c.PullRequest(ctx, "org", "repo", 4)
+
+	note := webhooktest.PullRequestNoteEvent("org", "repo", 4, "bob", "/lgtm")
+	note.Attributes.ID = ptr(json.Number("1530794"))
+	assert.Equal(t, nil, c.Apply(ctx, note))
+
+	now = now.Add(time.Minute)
+	e, err := c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int32(2), api.prCalls.Load())
+	assert.Equal(t, 1, len(e.Comments))
+	assert.Equal(t, "/lgtm", e.Comments[0].Body)
+
+	// a comment applied while reloading is neither lost nor duplicated
+	api.block = make(chan struct{})
+	now = now.Add(time.Minute)
+	done := make(chan *Entry)
+	go func() {
+		e, _ := c.PullRequest(ctx, "org", "repo", 4)
+		done <- e
+	}()
+	for api.prCalls.Load() == 2 {
+		time.Sleep(time.Millisecond)
+	}
+	reply := webhooktest.PullRequestNoteEvent("org", "repo", 4, "alice", "thanks")
+	reply.Attributes.ID = ptr(json.Number("1530795"))
+	assert.Equal(t, nil, c.Apply(ctx, reply))
+	close(api.block)
+
+	e = <-done
+	assert.Equal(t, 2, len(e.Comments))
+	assert.Equal(t, "thanks", e.Comments[1].Body)
+}
+
+func TestCacheApplyWhileLoading(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	api := &fakeAPI{block: make(chan struct{})}
+	c := newTestCache(api, &now)
+
+	done := make(chan *Entry)
+	go func() {
+		e, _ := c.PullRequest(ctx, "org", "repo", 4)
+		done <- e
+	}()
+	for api.prCalls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the event arrives after the API answered with the old title
+	pr := webhooktest.PullRequestEvent("org", "repo", 4, "update", "alice", "main", "dev")
+	pr.Attributes.Title = ptr("new title")
+	pr.Labels = []*openapi.Label{{Name: "approved"}}
+	assert.Equal(t, nil, c.Apply(ctx, pr))
+	close(api.block)
+
+	e := <-done
+	assert.Equal(t, "new title", *e.PullRequest.Title)
+	assert.Equal(t, []string{"approved"}, e.LabelNames())
+	e, _ = c.PullRequest(ctx, "org", "repo", 4)
+	assert.Equal(t, "new title", *e.PullRequest.Title)
+	assert.Equal(t, int32(1), api.prCalls.Load())
+}
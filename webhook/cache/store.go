@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// Store keeps cache entries. Entries passed to Put are not modified afterwards and
+// entries returned by Get must not be modified by the caller, so implementations
+// may keep the pointers. A Store backed by a shared service can serialize entries as JSON.
+type Store interface {
+	Get(ctx context.Context, key Key) (*Entry, bool, error)
+	Put(ctx context.Context, entry *Entry) error
+	Delete(ctx context.Context, key Key) error
+}
+
+// MemoryStore is an in-process Store.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[Key]*Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[Key]*Entry{}}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key Key) (*Entry, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[key]
+	return e, ok, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, entry *Entry) error {
+	s.mu.Lock()
+	s.entries[entry.Key] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key Key) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of entries.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
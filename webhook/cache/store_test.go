@@ -0,0 +1,42 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	key := Key{Kind: KindPullRequest, Owner: "org", Repo: "repo", Number: 1}
+	assert.Equal(t, "org/repo pull_request#1", key.String())
+
+	_, ok, err := s.Get(ctx, key)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, ok)
+
+	entry := &Entry{Key: key}
+	assert.Equal(t, nil, s.Put(ctx, entry))
+	got, ok, _ := s.Get(ctx, key)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, entry, got)
+	assert.Equal(t, 1, s.Len())
+
+	assert.Equal(t, nil, s.Delete(ctx, key))
+	assert.Equal(t, 0, s.Len())
+}
@@ -0,0 +1,309 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package cloudevents converts GitCode webhook events to CloudEvents 1.0 and encodes them
+// for HTTP in structured or binary mode.
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const (
+	SpecVersion = "1.0"
+	// TypePrefix starts the type of every converted event, followed by the event kind
+	// and the past tense of the action, such as "com.gitcode.pull_request.opened".
+	TypePrefix = "com.gitcode."
+
+	// ExtensionEventType carries the X-GitCode-Event header of the delivery.
+	ExtensionEventType = "gitcodeevent"
+	// ExtensionActor carries the login of the user who triggered the event.
+	ExtensionActor = "gitcodeactor"
+
+	jsonContentType = "application/json"
+)
+
+var (
+	ErrMissingID     = errors.New("cloudevent requires an id, the delivery is empty")
+	ErrMissingSource = errors.New("cloudevent requires a source, the event has no project")
+	errorNilEvent    = errors.New("event should be non-nil")
+)
+
+// Event is a CloudEvent. Data holds the JSON encoded GitCode payload.
+type Event struct {
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	Data            json.RawMessage
+	// Extensions holds extension attributes, keyed by lowercase alphanumeric names.
+	Extensions map[string]string
+}
+
+// pastTense maps GitCode actions to the names used in CloudEvent types. Member and
+// repository events name the whole event, their names are reduced to what happened.
+var pastTense = map[string]string{
+	"open":      "opened",
+	"reopen":    "reopened",
+	"close":     "closed",
+	"merge":     "merged",
+	"create":    "created",
+	"update":    "updated",
+	"delete":    "deleted",
+	"approve":   "approved",
+	"unapprove": "unapproved",
+	"submit":    "submitted",
+	"stop":      "stopped",
+	"publish":   "published",
+	"edit":      "edited",
+
+	"user_add_to_team":      "added",
+	"user_remove_from_team": "removed",
+	"user_update_for_team":  "updated",
+	"repository_update":     "updated",
+}
+
+// Type returns the CloudEvent type for an event of kind with action, such as
+// "com.gitcode.issue.closed". Actions without a known past tense are used as they are,
+// lower-cased with spaces replaced by "_". Without an action the type is "com.gitcode.<kind>".
+func Type(kind webhook.EventKind, action string) string {
+	t := TypePrefix + string(kind)
+	action = strings.ToLower(strings.Join(strings.Fields(action), "_"))
+	if action == "" {
+		return t
+	}
+
+	if past, ok := pastTense[action]; ok {
+		return t + "." + past
+	}
+	return t + "." + action
+}
+
+// Convert maps e, received in the delivery with the given ID and raw payload, to a
+// CloudEvent. The payload is used as data unchanged; when it is nil e is encoded instead.
+//
+// Notes get the type "com.gitcode.note.created", because the action of a note event
+// is the one of the issue or pull request it belongs to.
+func Convert(e webhook.Event, delivery string, payload []byte) (*Event, error) {
+	if e == nil {
+		return nil, errorNilEvent
+	}
+	if delivery == "" {
+		return nil, ErrMissingID
+	}
+
+	env := webhook.NewEnvelope(e)
+	source := env.RepoURL
+	if source == "" {
+		source = env.FullName()
+	}
+	if source == "" {
+		return nil, ErrMissingSource
+	}
+
+	action := env.Action
+	if env.Kind == webhook.EventKindNote {
+		action = "create"
+	}
+
+	ce := &Event{
+		ID:              delivery,
+		Source:          source,
+		Type:            Type(env.Kind, action),
+		Subject:         subject(env),
+		Time:            env.UpdatedAt,
+		DataContentType: jsonContentType,
+		Data:            payload,
+		Extensions: map[string]string{
+			ExtensionEventType: webhook.EventTypeOf(e),
+		},
+	}
+	if ce.Time.IsZero() {
+		ce.Time = env.CreatedAt
+	}
+	if env.Actor != "" {
+		ce.Extensions[ExtensionActor] = env.Actor
+	}
+	if ce.Data == nil {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		ce.Data = data
+	}
+	return ce, nil
+}
+
+// FromContext converts e using the delivery a webhook.Dispatcher stores in the handler context.
+func FromContext(ctx context.Context, e webhook.Event) (*Event, error) {
+	d, ok := webhook.DeliveryFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingID
+	}
+	return Convert(e, d.GUID, d.Payload)
+}
+
+func subject(env *webhook.Envelope) string {
+	switch env.Target {
+	case webhook.TargetPullRequest:
+		if env.Number > 0 {
+			return "pull_requests/" + strconv.Itoa(env.Number)
+		}
+	case webhook.TargetIssue:
+		if env.Number > 0 {
+			return "issues/" + strconv.Itoa(env.Number)
+		}
+	case webhook.TargetCommit:
+		if env.SHA != "" {
+			return "commits/" + env.SHA
+		}
+	}
+	return ""
+}
+
+// Validate checks the attributes required by the specification.
+func (ce *Event) Validate() error {
+	switch {
+	case ce.ID == "":
+		return ErrMissingID
+	case ce.Source == "":
+		return ErrMissingSource
+	case ce.Type == "":
+		return errors.New("cloudevent requires a type")
+	}
+	for name := range ce.Extensions {
+		if !validExtensionName(name) {
+			return fmt.Errorf("invalid cloudevent extension name %q", name)
+		}
+	}
+	return nil
+}
+
+func validExtensionName(name string) bool {
+	if name == "" || len(name) > 20 || reserved[name] {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+var reserved = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"subject":         true,
+	"time":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"data":            true,
+	"data_base64":     true,
+}
+
+// MarshalJSON encodes the event in the JSON format used by structured mode.
+func (ce *Event) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, 8+len(ce.Extensions))
+	for k, v := range ce.Extensions {
+		m[k] = v
+	}
+	m["specversion"] = SpecVersion
+	m["id"] = ce.ID
+	m["source"] = ce.Source
+	m["type"] = ce.Type
+	if ce.Subject != "" {
+		m["subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		m["time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	if ce.DataContentType != "" {
+		m["datacontenttype"] = ce.DataContentType
+	}
+	if ce.Data != nil {
+		m["data"] = ce.Data
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes an event in the JSON format. Only JSON data is supported.
+func (ce *Event) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	out := Event{Extensions: map[string]string{}}
+	for k, raw := range m {
+		if k == "data" {
+			out.Data = raw
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("cloudevent attribute %s: %w", k, err)
+		}
+		if err := out.set(k, s); err != nil {
+			return err
+		}
+	}
+	*ce = out
+	return nil
+}
+
+func (ce *Event) set(name, value string) error {
+	switch name {
+	case "specversion":
+		if value != SpecVersion {
+			return fmt.Errorf("unsupported cloudevent specversion %q", value)
+		}
+	case "id":
+		ce.ID = value
+	case "source":
+		ce.Source = value
+	case "type":
+		ce.Type = value
+	case "subject":
+		ce.Subject = value
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("cloudevent attribute time: %w", err)
+		}
+		ce.Time = t
+	case "datacontenttype":
+		ce.DataContentType = value
+	case "data_base64", "dataschema":
+		return fmt.Errorf("unsupported cloudevent attribute %s", name)
+	default:
+		if ce.Extensions == nil {
+			ce.Extensions = map[string]string{}
+		}
+		ce.Extensions[name] = value
+	}
+	return nil
+}
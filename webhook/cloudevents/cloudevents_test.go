@@ -0,0 +1,126 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/webhooktest"
+)
+
+func TestType(t *testing.T) {
+	testCases := []struct {
+		kind   webhook.EventKind
+		action string
+		want   string
+	}{
+		{webhook.EventKindPullRequest, "open", "com.gitcode.pull_request.opened"},
+		{webhook.EventKindPullRequest, "reopen", "com.gitcode.pull_request.reopened"},
+		{webhook.EventKindPullRequest, "merge", "com.gitcode.pull_request.merged"},
+		{webhook.EventKindIssue, "close", "com.gitcode.issue.closed"},
+		{webhook.EventKindPush, "update", "com.gitcode.push.updated"},
+		{webhook.EventKindPipeline, "Stop", "com.gitcode.pipeline.stopped"},
+		{webhook.EventKindPullRequest, "approved", "com.gitcode.pull_request.approved"},
+		{webhook.EventKindMember, "user_add_to_team", "com.gitcode.member.added"},
+		{webhook.EventKindMember, "user_remove_from_team", "com.gitcode.member.removed"},
+		{webhook.EventKindMember, "user_update_for_team", "com.gitcode.member.updated"},
+		{webhook.EventKindRepository, "repository_update", "com.gitcode.repository.updated"},
+		{webhook.EventKindRelease, "create", "com.gitcode.release.created"},
+		{webhook.EventKindMember, "user add to group", "com.gitcode.member.user_add_to_group"},
+		{webhook.EventKindIssue, "label", "com.gitcode.issue.label"},
+		{webhook.EventKindJob, "", "com.gitcode.job"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, Type(tc.kind, tc.action))
+	}
+}
+
+func TestConvert(t *testing.T) {
+	pr := webhooktest.PullRequestEvent("org", "repo", 4, "open", "alice", "main", "dev")
+	updated := openapi.Timestamp(time.Date(2024, 10, 26, 10, 0, 0, 0, time.UTC))
+	pr.Attributes.UpdatedTime = &updated
+	payload := webhooktest.Payload(pr)
+
+	ce, err := Convert(pr, "delivery-1", payload)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "delivery-1", ce.ID)
+	assert.Equal(t, "https://gitcode.com/org/repo", ce.Source)
+	assert.Equal(t, "com.gitcode.pull_request.opened", ce.Type)
+	assert.Equal(t, "pull_requests/4", ce.Subject)
+	assert.Equal(t, time.Time(updated), ce.Time)
+	assert.Equal(t, json.RawMessage(payload), ce.Data)
+	assert.Equal(t, webhook.EventTypePullRequest, ce.Extensions[ExtensionEventType])
+	assert.Equal(t, "alice", ce.Extensions[ExtensionActor])
+	assert.Equal(t, nil, ce.Validate())
+
+	ce, _ = Convert(webhooktest.IssueEvent("org", "repo", 2, "close", "bob"), "d", nil)
+	assert.Equal(t, "com.gitcode.issue.closed", ce.Type)
+	assert.Equal(t, "issues/2", ce.Subject)
+	assert.Equal(t, true, ce.Time.IsZero())
+	assert.Equal(t, true, json.Valid(ce.Data))
+
+	ce, _ = Convert(webhooktest.PullRequestNoteEvent("org", "repo", 4, "bob", "/lgtm"), "d", nil)
+	assert.Equal(t, "com.gitcode.note.created", ce.Type)
+	assert.Equal(t, "pull_requests/4", ce.Subject)
+	assert.Equal(t, "bob", ce.Extensions[ExtensionActor])
+
+	ce, _ = Convert(webhooktest.PushEvent("org", "repo", "refs/heads/main", "a1", "b2", "carol"), "d", nil)
+	assert.Equal(t, "com.gitcode.push.updated", ce.Type)
+	assert.Equal(t, "commits/b2", ce.Subject)
+
+	_, err = Convert(pr, "", payload)
+	assert.Equal(t, ErrMissingID, err)
+	_, err = Convert(new(webhook.PushEvent), "d", nil)
+	assert.Equal(t, ErrMissingSource, err)
+	_, err = Convert(nil, "d", nil)
+	assert.Equal(t, errorNilEvent, err)
+
+	_, err = FromContext(context.Background(), pr)
+	assert.Equal(t, ErrMissingID, err)
+}
+
+func TestEventJSON(t *testing.T) {
+	ce := &Event{
+		ID:              "1",
+		Source:          "https://gitcode.com/org/repo",
+		Type:            "com.gitcode.issue.opened",
+		Time:            time.Date(2024, 10, 26, 10, 0, 0, 0, time.UTC),
+		DataContentType: jsonContentType,
+		Data:            json.RawMessage(`{"a":1}`),
+		Extensions:      map[string]string{ExtensionActor: "alice"},
+	}
+
+	data, err := json.Marshal(ce)
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, `{"specversion":"1.0","id":"1","source":"https://gitcode.com/org/repo",
+		"type":"com.gitcode.issue.opened","time":"2024-10-26T10:00:00Z",
+		"datacontenttype":"application/json","data":{"a":1},"gitcodeactor":"alice"}`, string(data))
+
+	decoded := new(Event)
+	assert.Equal(t, nil, json.Unmarshal(data, decoded))
+	assert.Equal(t, ce, decoded)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"specversion":"0.3"}`), decoded))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"specversion":"1.0","id":1}`), decoded))
+
+	ce.Extensions["Bad-Name"] = "x"
+	assert.NotNil(t, ce.Validate())
+}
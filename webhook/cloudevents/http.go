@@ -0,0 +1,147 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentTypeStructured is the Content-Type of structured mode requests.
+	ContentTypeStructured = "application/cloudevents+json"
+
+	headerPrefix      = "Ce-"
+	headerContentType = "Content-Type"
+)
+
+var errorNotCloudEvent = errors.New("request is not a cloudevent")
+
+// NewStructuredRequest returns a POST request carrying ce as a JSON document.
+func NewStructuredRequest(ctx context.Context, target string, ce *Event) (*http.Request, error) {
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(headerContentType, ContentTypeStructured+"; charset=utf-8")
+	return req, nil
+}
+
+// NewBinaryRequest returns a POST request with the attributes of ce in Ce-* headers
+// and the data as body.
+func NewBinaryRequest(ctx context.Context, target string, ce *Event) (*http.Request, error) {
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(ce.Data))
+	if err != nil {
+		return nil, err
+	}
+	SetBinaryHeaders(req.Header, ce)
+	return req, nil
+}
+
+// SetBinaryHeaders sets the binary mode headers of ce on h.
+func SetBinaryHeaders(h http.Header, ce *Event) {
+	set := func(name, value string) {
+		if value != "" {
+			h.Set(headerPrefix+name, encodeHeaderValue(value))
+		}
+	}
+
+	set("specversion", SpecVersion)
+	set("id", ce.ID)
+	set("source", ce.Source)
+	set("type", ce.Type)
+	set("subject", ce.Subject)
+	if !ce.Time.IsZero() {
+		set("time", ce.Time.Format(time.RFC3339Nano))
+	}
+	for k, v := range ce.Extensions {
+		set(k, v)
+	}
+	if ce.DataContentType != "" {
+		h.Set(headerContentType, ce.DataContentType)
+	}
+}
+
+// ReadRequest decodes the CloudEvent of a structured or binary mode request.
+func ReadRequest(r *http.Request) (*Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(headerContentType))
+	if mediaType == ContentTypeStructured {
+		ce := new(Event)
+		if err := json.Unmarshal(body, ce); err != nil {
+			return nil, err
+		}
+		return ce, ce.Validate()
+	}
+
+	if r.Header.Get(headerPrefix+"specversion") == "" {
+		return nil, errorNotCloudEvent
+	}
+	ce := &Event{DataContentType: r.Header.Get(headerContentType), Data: body}
+	for k, values := range r.Header {
+		k = http.CanonicalHeaderKey(k)
+		if !strings.HasPrefix(k, headerPrefix) || len(values) == 0 {
+			continue
+		}
+
+		v, err := url.PathUnescape(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("cloudevent header %s: %w", k, err)
+		}
+		if err := ce.set(strings.ToLower(strings.TrimPrefix(k, headerPrefix)), v); err != nil {
+			return nil, err
+		}
+	}
+	return ce, ce.Validate()
+}
+
+// encodeHeaderValue percent-encodes the characters the HTTP binding does not allow in
+// header values.
+func encodeHeaderValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEvent() *Event {
+	return &Event{
+		ID:              "delivery-1",
+		Source:          "https://gitcode.com/org/repo",
+		Type:            "com.gitcode.pull_request.opened",
+		Subject:         "pull_requests/4",
+		Time:            time.Date(2024, 10, 26, 10, 0, 0, 0, time.UTC),
+		DataContentType: jsonContentType,
+		Data:            json.RawMessage(`{"object_kind":"merge_request"}`),
+		Extensions:      map[string]string{ExtensionActor: "a b%\"ü"},
+	}
+}
+
+func TestStructuredRequest(t *testing.T) {
+	ce := newTestEvent()
+	req, err := NewStructuredRequest(context.Background(), "http://localhost/events", ce)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "application/cloudevents+json; charset=utf-8", req.Header.Get("Content-Type"))
+
+	got, err := ReadRequest(req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ce, got)
+
+	_, err = NewStructuredRequest(context.Background(), "http://localhost/events", &Event{Type: "t"})
+	assert.Equal(t, ErrMissingID, err)
+}
+
+func TestBinaryRequest(t *testing.T) {
+	ce := newTestEvent()
+	req, err := NewBinaryRequest(context.Background(), "http://localhost/events", ce)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "1.0", req.Header.Get("ce-specversion"))
+	assert.Equal(t, "delivery-1", req.Header.Get("ce-id"))
+	assert.Equal(t, "com.gitcode.pull_request.opened", req.Header.Get("ce-type"))
+	assert.Equal(t, "2024-10-26T10:00:00Z", req.Header.Get("ce-time"))
+	assert.Equal(t, "a%20b%25%22%C3%BC", req.Header.Get("ce-gitcodeactor"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	got, err := ReadRequest(req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ce, got)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost/events", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	_, err = ReadRequest(req)
+	assert.Equal(t, errorNotCloudEvent, err)
+}
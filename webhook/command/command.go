@@ -0,0 +1,151 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package command parses slash commands such as /lgtm or /assign @user from webhook
+// comments and routes them to registered handlers.
+package command
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	commandPrefix = "/"
+
+	fenceBackticks = "```"
+	fenceTildes    = "~~~"
+	quotePrefix    = ">"
+	commentOpen    = "<!--"
+	commentClose   = "-->"
+)
+
+// Command is a slash command found in a comment.
+type Command struct {
+	// Name is the lower-cased command without the leading slash.
+	Name string
+	Args []string
+	// Line is the 1-based line of the comment the command was found on.
+	Line int
+	Raw  string
+}
+
+// Parse returns the commands in comment, one per line starting with a slash.
+// Lines in fenced or indented code blocks, block quotes and HTML comments are ignored,
+// so quoting someone else's command does not run it again.
+func Parse(comment string) []Command {
+	var commands []Command
+	var fence string
+	inComment := false
+
+	lines := strings.Split(strings.ReplaceAll(comment, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if inComment {
+			if strings.Contains(trimmed, commentClose) {
+				inComment = false
+			}
+			continue
+		}
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, fenceBackticks):
+			fence = fenceBackticks
+			continue
+		case strings.HasPrefix(trimmed, fenceTildes):
+			fence = fenceTildes
+			continue
+		case strings.HasPrefix(trimmed, commentOpen):
+			inComment = !strings.Contains(trimmed[len(commentOpen):], commentClose)
+			continue
+		case strings.HasPrefix(trimmed, quotePrefix), isIndentedCode(line):
+			continue
+		}
+
+		if c, ok := parseLine(trimmed); ok {
+			c.Line = i + 1
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func isIndentedCode(line string) bool {
+	return strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+}
+
+func parseLine(line string) (Command, bool) {
+	if !strings.HasPrefix(line, commandPrefix) {
+		return Command{}, false
+	}
+
+	rest := line[len(commandPrefix):]
+	if rest == "" || unicode.IsSpace(rune(rest[0])) {
+		return Command{}, false
+	}
+
+	fields := splitArgs(rest)
+	if len(fields) == 0 || !isName(fields[0]) {
+		return Command{}, false
+	}
+
+	return Command{
+		Name: strings.ToLower(fields[0]),
+		Args: fields[1:],
+		Raw:  line,
+	}, true
+}
+
+func isName(s string) bool {
+	for i, r := range s {
+		if unicode.IsLetter(r) || (i > 0 && (unicode.IsDigit(r) || r == '-' || r == '_')) {
+			continue
+		}
+		return false
+	}
+	return s != ""
+}
+
+// splitArgs splits s on white space, keeping double-quoted arguments together.
+func splitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted, started := false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted, started = !quoted, true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args
+}
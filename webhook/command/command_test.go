@@ -0,0 +1,46 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	comment := "Looks good to me.\r\n" +
+		"/lgtm\n" +
+		"  /assign @alice \"bob smith\"\n" +
+		"> /approve\n" +
+		"```\n/hold\n```\n" +
+		"~~~sh\n/retest\n~~~\n" +
+		"    /close\n" +
+		"<!-- /reopen\n/kind bug -->\n" +
+		"<!-- inline --> \n" +
+		"/LABEL kind/bug\n" +
+		"/ not-a-command\n" +
+		"/123\n" +
+		"path /lgtm in text\n"
+
+	got := Parse(comment)
+	assert.Equal(t, []Command{
+		{Name: "lgtm", Args: []string{}, Line: 2, Raw: "/lgtm"},
+		{Name: "assign", Args: []string{"@alice", "bob smith"}, Line: 3, Raw: "/assign @alice \"bob smith\""},
+		{Name: "label", Args: []string{"kind/bug"}, Line: 15, Raw: "/LABEL kind/bug"},
+	}, got)
+
+	assert.Equal(t, 0, len(Parse("")))
+	assert.Equal(t, 0, len(Parse("```\n/lgtm")))
+}
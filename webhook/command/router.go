@@ -0,0 +1,383 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+// Permission is the access level a commenter needs for a command.
+type Permission int
+
+const (
+	PermissionNone Permission = iota
+	PermissionRead
+	PermissionWrite
+	PermissionAdmin
+)
+
+var permissionNames = map[Permission]string{
+	PermissionNone:  "none",
+	PermissionRead:  "read",
+	PermissionWrite: "write",
+	PermissionAdmin: "admin",
+}
+
+func (p Permission) String() string {
+	if name, ok := permissionNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Permission(%d)", int(p))
+}
+
+// ParsePermission maps a permission returned by GetRepoMemberPermission to a Permission,
+// the same way webhook.ParseAssociation does.
+func ParsePermission(s string) Permission {
+	return permissionOf(webhook.ParseAssociation(s))
+}
+
+func permissionOf(a webhook.Association) Permission {
+	switch a {
+	case webhook.AssociationAdmin:
+		return PermissionAdmin
+	case webhook.AssociationWrite:
+		return PermissionWrite
+	case webhook.AssociationRead:
+		return PermissionRead
+	default:
+		return PermissionNone
+	}
+}
+
+// Target selects the comments a command is accepted on.
+type Target int
+
+const (
+	TargetAny Target = iota
+	TargetPullRequest
+	TargetIssue
+)
+
+const (
+	noteKindPullRequest = "MergeRequest"
+	noteKindIssue       = "Issue"
+)
+
+var (
+	errorEmptyName       = errors.New("command name should be non-empty")
+	errorNilHandler      = errors.New("command handler should be non-nil")
+	errorNoCommenter     = errors.New("no commenter configured for the comment target")
+	errorUnknownTarget   = errors.New("comment is neither on a pull request nor on an issue")
+	errorNoPermissionAPI = errors.New("command requires a permission but no PermissionChecker is configured")
+
+	// ErrDuplicateCommand is returned by Register when a name or alias is already registered.
+	ErrDuplicateCommand = errors.New("command already registered")
+)
+
+// PermissionChecker is implemented by *openapi.RepositoryService.
+type PermissionChecker interface {
+	GetRepoMemberPermission(ctx context.Context, owner, repo, login string) (*openapi.User, [2]bool, error)
+}
+
+// PullRequestCommenter is implemented by *openapi.PullRequestsService.
+type PullRequestCommenter interface {
+	CreatePullRequestComment(ctx context.Context, owner, repo, number string, comment *openapi.PullRequestCommentRequest) (*openapi.SimpleComment, bool, error)
+}
+
+// IssueCommenter is implemented by *openapi.IssuesService.
+type IssueCommenter interface {
+	CreateIssueComment(ctx context.Context, owner, repo, number string, comment *openapi.IssueComment) (*openapi.IssueComment, bool, error)
+}
+
+var (
+	_ PermissionChecker    = (*openapi.RepositoryService)(nil)
+	_ PullRequestCommenter = (*openapi.PullRequestsService)(nil)
+	_ IssueCommenter       = (*openapi.IssuesService)(nil)
+)
+
+// Arg describes a positional argument of a command.
+type Arg struct {
+	Name     string
+	Required bool
+	// Values restricts the argument to one of the listed values when non-empty.
+	Values []string
+	// Variadic collects all remaining arguments. Only the last Arg can be variadic.
+	Variadic bool
+}
+
+// Handler is called for each accepted command.
+type Handler func(ctx context.Context, c *Context) error
+
+// Spec registers a command on a Router.
+type Spec struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Args        []Arg
+	Target      Target
+	// Permission is the minimum access level of the commenter on the repository.
+	Permission Permission
+	Handler    Handler
+}
+
+// Usage returns a one-line synopsis such as "/assign <user>...".
+func (s *Spec) Usage() string {
+	var b strings.Builder
+	b.WriteString(commandPrefix + s.Name)
+	for _, a := range s.Args {
+		name := a.Name
+		if len(a.Values) > 0 {
+			name = strings.Join(a.Values, "|")
+		}
+		if a.Variadic {
+			name += "..."
+		}
+		if a.Required {
+			b.WriteString(" <" + name + ">")
+		} else {
+			b.WriteString(" [" + name + "]")
+		}
+	}
+	return b.String()
+}
+
+// bind assigns args to the argument names of the spec.
+func (s *Spec) bind(args []string) (map[string][]string, error) {
+	bound := make(map[string][]string, len(s.Args))
+	i := 0
+	for _, a := range s.Args {
+		var values []string
+		switch {
+		case a.Variadic:
+			values = args[i:]
+			i = len(args)
+		case i < len(args):
+			values = args[i : i+1]
+			i++
+		}
+
+		if len(values) == 0 {
+			if a.Required {
+				return nil, fmt.Errorf("missing argument %s", a.Name)
+			}
+			continue
+		}
+		for _, v := range values {
+			if len(a.Values) > 0 && !contains(a.Values, v) {
+				return nil, fmt.Errorf("invalid %s %q, expected one of %s", a.Name, v, strings.Join(a.Values, ", "))
+			}
+		}
+		bound[a.Name] = values
+	}
+	if i < len(args) {
+		return nil, fmt.Errorf("unexpected argument %q", args[i])
+	}
+	return bound, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Context is passed to a Handler.
+type Context struct {
+	Event   *webhook.NoteEvent
+	Command Command
+	Spec    *Spec
+	// Permission is the commenter's access level, PermissionNone when the spec does not require one.
+	Permission Permission
+
+	args   map[string][]string
+	router *Router
+}
+
+// Arg returns the first value of the named argument, or an empty string.
+func (c *Context) Arg(name string) string {
+	if v := c.args[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Args returns all values of the named argument.
+func (c *Context) Args(name string) []string {
+	return c.args[name]
+}
+
+// Reply comments on the pull request or issue the command was posted on.
+func (c *Context) Reply(ctx context.Context, body string) error {
+	return c.router.reply(ctx, c.Event, body)
+}
+
+// Router runs the commands found in note events. Its HandleNote method can be registered
+// with webhook.Dispatcher.OnNote.
+type Router struct {
+	perms  PermissionChecker
+	prs    PullRequestCommenter
+	issues IssueCommenter
+
+	mu    sync.RWMutex
+	specs map[string]*Spec
+}
+
+// NewRouter creates a Router. Any of the clients can be nil when the commands do not need
+// them; with an *openapi.APIClient pass client.Repository, client.PullRequests and client.Issues.
+func NewRouter(perms PermissionChecker, prs PullRequestCommenter, issues IssueCommenter) *Router {
+	return &Router{
+		perms:  perms,
+		prs:    prs,
+		issues: issues,
+		specs:  map[string]*Spec{},
+	}
+}
+
+// Register adds a command under its name and aliases.
+func (r *Router) Register(spec Spec) error {
+	if spec.Name == "" {
+		return errorEmptyName
+	}
+	if spec.Handler == nil {
+		return errorNilHandler
+	}
+
+	names := append([]string{spec.Name}, spec.Aliases...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := r.specs[strings.ToLower(name)]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateCommand, name)
+		}
+	}
+	s := &spec
+	for _, name := range names {
+		r.specs[strings.ToLower(name)] = s
+	}
+	return nil
+}
+
+// HandleNote runs every registered command found in the comment of e. Unknown commands are
+// ignored. Invalid arguments and missing permissions are answered with a reply. Errors of
+// handlers are joined and returned after all commands ran.
+func (r *Router) HandleNote(ctx context.Context, e *webhook.NoteEvent) error {
+	comment := e.GetComment()
+	if comment == nil {
+		return nil
+	}
+
+	var errs []error
+	var perm *Permission
+	for _, cmd := range Parse(*comment) {
+		r.mu.RLock()
+		spec, ok := r.specs[cmd.Name]
+		r.mu.RUnlock()
+		if !ok || !matchTarget(spec.Target, e) {
+			continue
+		}
+
+		c := &Context{Event: e, Command: cmd, Spec: spec, router: r}
+		args, err := spec.bind(cmd.Args)
+		if err != nil {
+			errs = append(errs, r.reply(ctx, e, fmt.Sprintf("%s: %v. Usage: `%s`", commandPrefix+cmd.Name, err, spec.Usage())))
+			continue
+		}
+		c.args = args
+
+		if spec.Permission > PermissionNone {
+			if perm == nil {
+				p, err := r.permission(ctx, e)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				perm = &p
+			}
+			c.Permission = *perm
+			if c.Permission < spec.Permission {
+				errs = append(errs, r.reply(ctx, e, fmt.Sprintf("@%s %s requires %s permission on this repository.",
+					deref(e.GetCommenter()), commandPrefix+cmd.Name, spec.Permission)))
+				continue
+			}
+		}
+
+		if err := spec.Handler(ctx, c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", commandPrefix+cmd.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Router) permission(ctx context.Context, e *webhook.NoteEvent) (Permission, error) {
+	if r.perms == nil {
+		return PermissionNone, errorNoPermissionAPI
+	}
+	login := e.GetCommenter()
+	if login == nil {
+		return PermissionNone, nil
+	}
+
+	a, err := webhook.LookupAssociation(ctx, r.perms, deref(e.GetOrg()), deref(e.GetRepo()), *login)
+	return permissionOf(a), err
+}
+
+func (r *Router) reply(ctx context.Context, e *webhook.NoteEvent, body string) error {
+	owner, repo, number := deref(e.GetOrg()), deref(e.GetRepo()), deref(e.GetNumber())
+
+	var err error
+	switch deref(e.GetCommentKind()) {
+	case noteKindPullRequest:
+		if r.prs == nil {
+			return errorNoCommenter
+		}
+		_, _, err = r.prs.CreatePullRequestComment(ctx, owner, repo, number, &openapi.PullRequestCommentRequest{Body: body})
+	case noteKindIssue:
+		if r.issues == nil {
+			return errorNoCommenter
+		}
+		_, _, err = r.issues.CreateIssueComment(ctx, owner, repo, number, &openapi.IssueComment{Body: &body})
+	default:
+		return errorUnknownTarget
+	}
+	return err
+}
+
+func matchTarget(t Target, e *webhook.NoteEvent) bool {
+	switch t {
+	case TargetPullRequest:
+		return deref(e.GetCommentKind()) == noteKindPullRequest
+	case TargetIssue:
+		return deref(e.GetCommentKind()) == noteKindIssue
+	default:
+		return true
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,149 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/webhooktest"
+)
+
+type fakeClients struct {
+	permissions map[string]string
+	permErr     error
+	calls       int
+	prReplies   []string
+	issueReply  []string
+}
+
+func (f *fakeClients) GetRepoMemberPermission(_ context.Context, owner, repo, login string) (*openapi.User, [2]bool, error) {
+	f.calls++
+	if f.permErr != nil {
+		return nil, [2]bool{}, f.permErr
+	}
+	p, ok := f.permissions[login]
+	if !ok {
+		return new(openapi.User), [2]bool{false, true}, errors.New("404 Not Found")
+	}
+	return &openapi.User{Permission: &p}, [2]bool{true, false}, nil
+}
+
+func (f *fakeClients) CreatePullRequestComment(_ context.Context, owner, repo, number string, comment *openapi.PullRequestCommentRequest) (*openapi.SimpleComment, bool, error) {
+	f.prReplies = append(f.prReplies, owner+"/"+repo+"#"+number+": "+comment.Body)
+	return &openapi.SimpleComment{Body: comment.Body}, true, nil
+}
+
+func (f *fakeClients) CreateIssueComment(_ context.Context, owner, repo, number string, comment *openapi.IssueComment) (*openapi.IssueComment, bool, error) {
+	f.issueReply = append(f.issueReply, owner+"/"+repo+"#"+number+": "+*comment.Body)
+	return comment, true, nil
+}
+
+func TestRouterHandleNote(t *testing.T) {
+	clients := &fakeClients{permissions: map[string]string{"maintainer": "admin", "dev": "write", "guest": "read"}}
+	r := NewRouter(clients, clients, clients)
+
+	var ran []string
+	assert.Equal(t, nil, r.Register(Spec{
+		Name:       "lgtm",
+		Args:       []Arg{{Name: "action", Values: []string{"cancel"}}},
+		Target:     TargetPullRequest,
+		Permission: PermissionWrite,
+		Handler: func(ctx context.Context, c *Context) error {
+			ran = append(ran, "lgtm "+c.Arg("action")+" by "+c.Permission.String())
+			return nil
+		},
+	}))
+	assert.Equal(t, nil, r.Register(Spec{
+		Name:    "assign",
+		Aliases: []string{"cc"},
+		Args:    []Arg{{Name: "user", Required: true, Variadic: true}},
+		Handler: func(ctx context.Context, c *Context) error {
+			ran = append(ran, "assign "+strings.Join(c.Args("user"), ","))
+			return c.Reply(ctx, "assigned")
+		},
+	}))
+	failure := errors.New("label service down")
+	assert.Equal(t, nil, r.Register(Spec{
+		Name: "label",
+		Handler: func(ctx context.Context, c *Context) error {
+			return failure
+		},
+	}))
+
+	e := webhooktest.PullRequestNoteEvent("org", "repo", 7, "dev", "/lgtm\n/cc @a @b\n/assign\n/unknown\n> /lgtm cancel")
+	assert.Equal(t, nil, r.HandleNote(context.Background(), e))
+	assert.Equal(t, []string{"lgtm  by write", "assign @a,@b"}, ran)
+	assert.Equal(t, []string{
+		"org/repo#7: assigned",
+		"org/repo#7: /assign: missing argument user. Usage: `/assign <user...>`",
+	}, clients.prReplies)
+	assert.Equal(t, 1, clients.calls)
+
+	ran, clients.prReplies = nil, nil
+	e = webhooktest.PullRequestNoteEvent("org", "repo", 7, "guest", "/lgtm cancel\n/lgtm maybe")
+	assert.Equal(t, nil, r.HandleNote(context.Background(), e))
+	assert.Equal(t, 0, len(ran))
+	assert.Equal(t, []string{
+		"org/repo#7: @guest /lgtm requires write permission on this repository.",
+		"org/repo#7: /lgtm: invalid action \"maybe\", expected one of cancel. Usage: `/lgtm [cancel]`",
+	}, clients.prReplies)
+
+	e = webhooktest.PullRequestNoteEvent("org", "repo", 7, "stranger", "/lgtm")
+	assert.Equal(t, nil, r.HandleNote(context.Background(), e))
+	assert.Equal(t, "org/repo#7: @stranger /lgtm requires write permission on this repository.", clients.prReplies[2])
+
+	e = webhooktest.IssueNoteEvent("org", "repo", 3, "maintainer", "/lgtm\n/assign @c\n/label")
+	err := r.HandleNote(context.Background(), e)
+	assert.True(t, errors.Is(err, failure))
+	assert.Equal(t, []string{"assign @c"}, ran)
+	assert.Equal(t, []string{"org/repo#3: assigned"}, clients.issueReply)
+
+	clients.permErr = errors.New("timeout")
+	e = webhooktest.PullRequestNoteEvent("org", "repo", 7, "dev", "/lgtm")
+	assert.True(t, errors.Is(r.HandleNote(context.Background(), e), clients.permErr))
+
+	assert.Equal(t, nil, r.HandleNote(context.Background(), new(webhook.NoteEvent)))
+}
+
+func TestRouterRegister(t *testing.T) {
+	r := NewRouter(nil, nil, nil)
+	h := func(ctx context.Context, c *Context) error { return nil }
+
+	assert.Equal(t, errorEmptyName, r.Register(Spec{Handler: h}))
+	assert.Equal(t, errorNilHandler, r.Register(Spec{Name: "hold"}))
+	assert.Equal(t, nil, r.Register(Spec{Name: "hold", Aliases: []string{"wait"}, Handler: h}))
+	assert.True(t, errors.Is(r.Register(Spec{Name: "Wait", Handler: h}), ErrDuplicateCommand))
+
+	assert.Equal(t, nil, r.Register(Spec{Name: "approve", Permission: PermissionWrite, Handler: h}))
+	e := webhooktest.PullRequestNoteEvent("org", "repo", 1, "dev", "/approve")
+	assert.True(t, errors.Is(r.HandleNote(context.Background(), e), errorNoPermissionAPI))
+
+	e = webhooktest.PullRequestNoteEvent("org", "repo", 1, "dev", "/hold now")
+	assert.True(t, errors.Is(r.HandleNote(context.Background(), e), errorNoCommenter))
+}
+
+func TestParsePermission(t *testing.T) {
+	assert.Equal(t, PermissionAdmin, ParsePermission("Admin"))
+	assert.Equal(t, PermissionWrite, ParsePermission("push"))
+	assert.Equal(t, PermissionRead, ParsePermission("read"))
+	assert.Equal(t, PermissionNone, ParsePermission(""))
+	assert.Equal(t, "Permission(9)", Permission(9).String())
+}
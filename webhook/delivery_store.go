@@ -0,0 +1,312 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errorInvalidDeliveryID = errors.New("delivery id should be non-empty and must not contain whitespace")
+
+// validDeliveryID reports whether id can be stored, the same IDs are accepted by all stores.
+func validDeliveryID(id string) bool {
+	return strings.TrimSpace(id) != "" && !strings.ContainsAny(id, " \t\r\n")
+}
+
+// DeliveryStore remembers the X-GitCode-Delivery IDs of requests that have been accepted.
+// Implementations must be safe for concurrent use.
+type DeliveryStore interface {
+	// Seen records id and reports whether it had already been recorded.
+	Seen(id string) (bool, error)
+	// Forget removes id, so that GitCode retrying a delivery that could not be handled
+	// is accepted as new. Forgetting an unknown id is not an error.
+	Forget(id string) error
+}
+
+// DuplicateDeliveryMode controls what GitCodeAuthentication.Auth does with a delivery
+// ID that its DeliveryStore has already seen.
+type DuplicateDeliveryMode int
+
+const (
+	// DuplicateDeliveryReject fails authentication with 409 Conflict.
+	DuplicateDeliveryReject DuplicateDeliveryMode = iota
+	// DuplicateDeliveryFlag accepts the request and reports it through IsRedelivery.
+	DuplicateDeliveryFlag
+)
+
+// MemoryDeliveryStore is an in-memory DeliveryStore that keeps at most capacity IDs,
+// evicting the least recently seen one first, and forgets IDs older than ttl.
+type MemoryDeliveryStore struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently seen
+	entries map[string]*list.Element
+}
+
+type deliveryEntry struct {
+	id     string
+	seenAt time.Time
+}
+
+// NewMemoryDeliveryStore creates a MemoryDeliveryStore. A capacity or ttl <= 0 means no limit.
+func NewMemoryDeliveryStore(capacity int, ttl time.Duration) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (s *MemoryDeliveryStore) Seen(id string) (bool, error) {
+	if !validDeliveryID(id) {
+		return false, errorInvalidDeliveryID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+
+	if e, ok := s.entries[id]; ok {
+		e.Value.(*deliveryEntry).seenAt = now
+		s.order.MoveToFront(e)
+		return true, nil
+	}
+
+	s.entries[id] = s.order.PushFront(&deliveryEntry{id: id, seenAt: now})
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return false, nil
+}
+
+func (s *MemoryDeliveryStore) Forget(id string) error {
+	if !validDeliveryID(id) {
+		return errorInvalidDeliveryID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[id]; ok {
+		s.remove(e)
+	}
+	return nil
+}
+
+// Len returns the number of IDs currently remembered.
+func (s *MemoryDeliveryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(s.now())
+	return s.order.Len()
+}
+
+func (s *MemoryDeliveryStore) expire(now time.Time) {
+	if s.ttl <= 0 {
+		return
+	}
+	for e := s.order.Back(); e != nil && now.Sub(e.Value.(*deliveryEntry).seenAt) >= s.ttl; e = s.order.Back() {
+		s.remove(e)
+	}
+}
+
+func (s *MemoryDeliveryStore) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(*deliveryEntry).id)
+}
+
+// fileCompactMinLines is the size, in lines, below which FileDeliveryStore never compacts its file.
+const fileCompactMinLines = 1024
+
+// FileDeliveryStore is a DeliveryStore that survives restarts by appending every new
+// ID, and every forgotten one, to a file. Expired IDs are dropped from memory as the
+// store runs, and the file is rewritten without them once most of its lines are stale.
+type FileDeliveryStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	seen    map[string]time.Time
+	lines   int // lines in file, live or not
+	sweptAt time.Time
+}
+
+// NewFileDeliveryStore opens or creates the store at path. A ttl <= 0 keeps IDs forever.
+func NewFileDeliveryStore(path string, ttl time.Duration) (*FileDeliveryStore, error) {
+	s := &FileDeliveryStore{
+		ttl:  ttl,
+		now:  time.Now,
+		path: path,
+		seen: map[string]time.Time{},
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the live entries of the file and rewrites it without the others.
+// A line is either "<unix nano> <id>" recording id or "- <id>" forgetting it.
+func (s *FileDeliveryStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	now := s.now()
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == "-" {
+			delete(s.seen, fields[1])
+			continue
+		}
+		nano, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if seenAt := time.Unix(0, nano); !s.expired(seenAt, now) {
+			s.seen[fields[1]] = seenAt
+		}
+	}
+	return s.compact()
+}
+
+// compact replaces the file with one holding only the entries in memory.
+func (s *FileDeliveryStore) compact() error {
+	var live strings.Builder
+	for id, seenAt := range s.seen {
+		fmt.Fprintf(&live, "%d %s\n", seenAt.UnixNano(), id)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(live.String()), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+	s.file, s.lines = file, len(s.seen)
+	return nil
+}
+
+// sweep drops expired IDs, at most twice per ttl, and compacts the file once fewer
+// than half of its lines are live.
+func (s *FileDeliveryStore) sweep(now time.Time) error {
+	if s.ttl > 0 && now.Sub(s.sweptAt) >= s.ttl/2 {
+		for id, seenAt := range s.seen {
+			if s.expired(seenAt, now) {
+				delete(s.seen, id)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	if s.lines < fileCompactMinLines || s.lines <= 2*len(s.seen) {
+		return nil
+	}
+	return s.compact()
+}
+
+func (s *FileDeliveryStore) Seen(id string) (bool, error) {
+	if !validDeliveryID(id) {
+		return false, errorInvalidDeliveryID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if err := s.sweep(now); err != nil {
+		return false, err
+	}
+	if seenAt, ok := s.seen[id]; ok && !s.expired(seenAt, now) {
+		return true, nil
+	}
+
+	if _, err := fmt.Fprintf(s.file, "%d %s\n", now.UnixNano(), id); err != nil {
+		return false, err
+	}
+	s.seen[id] = now
+	s.lines++
+	return false, nil
+}
+
+func (s *FileDeliveryStore) Forget(id string) error {
+	if !validDeliveryID(id) {
+		return errorInvalidDeliveryID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[id]; !ok {
+		return nil
+	}
+	if _, err := fmt.Fprintf(s.file, "- %s\n", id); err != nil {
+		return err
+	}
+	delete(s.seen, id)
+	s.lines++
+	return nil
+}
+
+// Len returns the number of IDs currently remembered, including expired ones not yet swept.
+func (s *FileDeliveryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.seen)
+}
+
+// Close closes the underlying file.
+func (s *FileDeliveryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileDeliveryStore) expired(seenAt, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(seenAt) >= s.ttl
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeliveryStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryDeliveryStore(2, time.Minute)
+	s.now = func() time.Time { return now }
+
+	for _, id := range []string{"", "  ", "\t", "has space"} {
+		_, err := s.Seen(id)
+		assert.Equal(t, errorInvalidDeliveryID, err)
+		assert.Equal(t, errorInvalidDeliveryID, s.Forget(id))
+	}
+
+	seen, _ := s.Seen("a")
+	assert.Equal(t, false, seen)
+	seen, _ = s.Seen("a")
+	assert.Equal(t, true, seen)
+
+	_, _ = s.Seen("b")
+	_, _ = s.Seen("c") // evicts "a", the least recently seen
+	assert.Equal(t, 2, s.Len())
+	seen, _ = s.Seen("a")
+	assert.Equal(t, false, seen)
+
+	assert.Equal(t, nil, s.Forget("a"))
+	assert.Equal(t, nil, s.Forget("unknown"))
+	assert.Equal(t, errorInvalidDeliveryID, s.Forget(""))
+	assert.Equal(t, 1, s.Len())
+	seen, _ = s.Seen("a")
+	assert.Equal(t, false, seen)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 0, s.Len())
+	seen, _ = s.Seen("c")
+	assert.Equal(t, false, seen)
+}
+
+func TestFileDeliveryStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries")
+	now := time.Unix(1700000000, 0)
+
+	s, err := NewFileDeliveryStore(path, time.Hour)
+	assert.Equal(t, nil, err)
+	s.now = func() time.Time { return now }
+
+	for _, id := range []string{"", "  ", "\t", "has space"} {
+		_, err = s.Seen(id)
+		assert.Equal(t, errorInvalidDeliveryID, err)
+	}
+
+	seen, err := s.Seen("a")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, seen)
+	seen, _ = s.Seen("a")
+	assert.Equal(t, true, seen)
+	assert.Equal(t, nil, s.Close())
+
+	// entries older than the ttl are dropped when the file is reopened
+	old := strconv.FormatInt(time.Now().Add(-2*time.Hour).UnixNano(), 10) + " b\n"
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = f.WriteString(old + "garbage\n")
+	_ = f.Close()
+
+	s, err = NewFileDeliveryStore(path, 0)
+	assert.Equal(t, nil, err)
+	defer s.Close()
+	seen, _ = s.Seen("a")
+	assert.Equal(t, true, seen)
+	seen, _ = s.Seen("b")
+	assert.Equal(t, true, seen)
+
+	// a forgotten ID stays forgotten after a restart
+	assert.Equal(t, nil, s.Forget("a"))
+	assert.Equal(t, nil, s.Forget("unknown"))
+
+	s2, err := NewFileDeliveryStore(path, time.Hour)
+	assert.Equal(t, nil, err)
+	defer s2.Close()
+	seen, _ = s2.Seen("b")
+	assert.Equal(t, false, seen)
+	seen, _ = s2.Seen("a")
+	assert.Equal(t, false, seen)
+}
+
+func TestFileDeliveryStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries")
+	now := time.Unix(1700000000, 0)
+
+	s, err := NewFileDeliveryStore(path, time.Minute)
+	assert.Equal(t, nil, err)
+	defer s.Close()
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 3*fileCompactMinLines; i++ {
+		now = now.Add(time.Second)
+		_, err = s.Seen(strconv.Itoa(i))
+		assert.Equal(t, nil, err)
+	}
+
+	// only the IDs of the last minute are kept, in memory and on disk
+	assert.LessOrEqual(t, s.Len(), 90)
+	data, _ := os.ReadFile(path)
+	assert.Less(t, bytes.Count(data, []byte("\n")), fileCompactMinLines+90)
+
+	seen, _ := s.Seen(strconv.Itoa(3*fileCompactMinLines - 1))
+	assert.Equal(t, true, seen)
+	seen, _ = s.Seen("0")
+	assert.Equal(t, false, seen)
+}
+
+func TestGitCodeAuthenticationReplay(t *testing.T) {
+	newRequest := func(guid string, ts int64) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/replay", bytes.NewBufferString(payloadData))
+		req.Header.Set(headerUserAgent, headerUserAgentValue)
+		req.Header.Set(headerContentTypeName, headerContentTypeJsonValue)
+		req.Header.Set(headerEventType, noteEvent)
+		req.Header.Set(headerEventGUID, guid)
+		req.Header.Set(headerEventToken, "sha256=f585860d0ca237e0550da0e166370b9c372e8aeb2e639b0ac9884cd52681c576")
+		if ts != 0 {
+			req.Header.Set(headerEventTimestamp, strconv.FormatInt(ts, 10))
+		}
+		return req
+	}
+
+	a := GitCodeAuthentication{signKey: "1234"}
+	a.SetDeliveryStore(NewMemoryDeliveryStore(10, 0), DuplicateDeliveryReject)
+
+	err, _ := a.Auth(httptest.NewRecorder(), newRequest("1", 0))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, a.IsRedelivery())
+
+	// a forgotten ID is accepted again
+	assert.Equal(t, nil, a.ForgetDelivery())
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("1", 0))
+	assert.Equal(t, nil, err)
+
+	w := httptest.NewRecorder()
+	err, _ = a.Auth(w, newRequest("1", 0))
+	assert.Equal(t, duplicateDeliveryErrorMessage, err.Error())
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// only the request that recorded the ID forgets it
+	assert.Equal(t, nil, a.ForgetDelivery())
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("1", 0))
+	assert.Equal(t, duplicateDeliveryErrorMessage, err.Error())
+
+	a.SetDeliveryStore(NewMemoryDeliveryStore(10, 0), DuplicateDeliveryFlag)
+	_, _ = a.Auth(httptest.NewRecorder(), newRequest("2", 0))
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("2", 0))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, a.IsRedelivery())
+	assert.Equal(t, nil, a.ForgetDelivery())
+	_, _ = a.Auth(httptest.NewRecorder(), newRequest("2", 0))
+	assert.Equal(t, true, a.IsRedelivery())
+
+	a.SetDeliveryStore(nil, DuplicateDeliveryReject)
+	a.SetMaxClockSkew(time.Minute)
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("3", 0))
+	assert.Equal(t, headerTimestampErrorMessage, err.Error())
+
+	w = httptest.NewRecorder()
+	err, _ = a.Auth(w, newRequest("3", time.Now().Add(-time.Hour).UnixMilli()))
+	assert.Equal(t, clockSkewErrorMessage, err.Error())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	err, _ = a.Auth(httptest.NewRecorder(), newRequest("3", time.Now().UnixMilli()))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "3", a.GetEventGUID())
+}
@@ -0,0 +1,418 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	payloadParseErrorMessage = "400 Bad Request: Failed to parse request body"
+	handlerErrorMessage      = "500 Internal Server Error: Webhook handler failed"
+	queueFullErrorMessage    = "503 Service Unavailable: Webhook queue is full"
+	queueClosedErrorMessage  = "503 Service Unavailable: Webhook queue is closed"
+	enqueueErrorMessage      = "500 Internal Server Error: Failed to enqueue webhook"
+	filterErrorMessage       = "500 Internal Server Error: Failed to evaluate webhook filter"
+)
+
+type (
+	PullRequestHandler func(ctx context.Context, e *PullRequestEvent) error
+	IssueHandler       func(ctx context.Context, e *IssueEvent) error
+	NoteHandler        func(ctx context.Context, e *NoteEvent) error
+	PushHandler        func(ctx context.Context, e *PushEvent) error
+	TagPushHandler     func(ctx context.Context, e *TagPushEvent) error
+	ReleaseHandler     func(ctx context.Context, e *ReleaseEvent) error
+	PipelineHandler    func(ctx context.Context, e *PipelineEvent) error
+	JobHandler         func(ctx context.Context, e *JobEvent) error
+	WikiPageHandler    func(ctx context.Context, e *WikiPageEvent) error
+	MemberHandler      func(ctx context.Context, e *MemberEvent) error
+	RepositoryHandler  func(ctx context.Context, e *RepositoryEvent) error
+
+	// EventHandler receives any event type through the common Event interface.
+	EventHandler func(ctx context.Context, e Event) error
+)
+
+// Delivery describes the webhook request an event was parsed from.
+type Delivery struct {
+	EventType string
+	GUID      string
+	Payload   []byte
+	// Redelivery is set when the delivery ID had already been seen, see DuplicateDeliveryFlag.
+	Redelivery bool
+	// Attempt counts the tries of a queued job, starting at 1. It is 0 when handled synchronously.
+	Attempt int
+}
+
+type deliveryContextKey struct{}
+
+// DeliveryFromContext returns the delivery passed to handlers registered on a Dispatcher.
+func DeliveryFromContext(ctx context.Context) (*Delivery, bool) {
+	d, ok := ctx.Value(deliveryContextKey{}).(*Delivery)
+	return d, ok
+}
+
+type route struct {
+	actions map[string]struct{}
+	filter  *Filter
+	handle  func(ctx context.Context, event Event) error
+}
+
+func (rt *route) match(ctx context.Context, event Event, r AssociationResolver) (bool, error) {
+	if rt.filter != nil {
+		return rt.filter.Match(ctx, event, r)
+	}
+	if len(rt.actions) == 0 {
+		return true, nil
+	}
+	action := event.GetAction()
+	if action == nil {
+		return false, nil
+	}
+	_, ok := rt.actions[*action]
+	return ok, nil
+}
+
+// Dispatcher is an http.Handler that authenticates GitCode webhook requests,
+// parses the payload and calls the handlers registered for the event type.
+//
+// Responses: 200 when all matched handlers succeeded, 204 when no handler matched,
+// 400 when the payload can not be parsed, 500 when a handler or a filter returned an error.
+// With a Queue set, 202 when the event was queued and 503 when the queue is full or closed.
+// Authentication failures are answered by GitCodeAuthentication.Auth.
+type Dispatcher struct {
+	auth     GitCodeAuthentication
+	unknown  UnknownFieldsFunc
+	queue    Queue
+	resolver AssociationResolver
+	observer Observer
+
+	mu     sync.RWMutex
+	routes map[string][]*route
+}
+
+func NewDispatcher(signKey []byte) (*Dispatcher, error) {
+	var a GitCodeAuthentication
+	if err := a.SetSignKey(signKey); err != nil {
+		return nil, err
+	}
+
+	return NewDispatcherWithAuth(&a), nil
+}
+
+// NewDispatcherWithAuth creates a Dispatcher that authenticates requests with a copy of
+// the configuration of a, such as the secrets set with SetSignKeys.
+func NewDispatcherWithAuth(a *GitCodeAuthentication) *Dispatcher {
+	return &Dispatcher{
+		auth:   a.config(),
+		routes: map[string][]*route{},
+	}
+}
+
+// SetStrict passes the payload fields the event structs do not model to f, see GitCodeAccessor.SetStrict.
+func (d *Dispatcher) SetStrict(f UnknownFieldsFunc) {
+	d.unknown = f
+}
+
+// SetObserver reports received, rejected, parsed and handled deliveries to o, nil disables it.
+func (d *Dispatcher) SetObserver(o Observer) {
+	d.observer = o
+	d.auth.SetObserver(o)
+}
+
+// SetQueue makes the Dispatcher acknowledge deliveries with 202 as soon as they are
+// authenticated and parsed, and pass them to q instead of calling the handlers.
+// q must call Handle for each job; a nil q restores synchronous handling.
+func (d *Dispatcher) SetQueue(q Queue) {
+	d.mu.Lock()
+	d.queue = q
+	d.mu.Unlock()
+}
+
+// SetAssociationResolver sets the resolver used by filters registered with OnMatch
+// that check the author association.
+func (d *Dispatcher) SetAssociationResolver(r AssociationResolver) {
+	d.mu.Lock()
+	d.resolver = r
+	d.mu.Unlock()
+}
+
+// OnPullRequest registers h for "Merge Request Hook" events. When actions are given,
+// h is only called for events whose GetAction matches one of them.
+func (d *Dispatcher) OnPullRequest(h PullRequestHandler, actions ...string) {
+	d.register(pullRequestEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*PullRequestEvent))
+	}, actions)
+}
+
+// OnIssue registers h for "Issue Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnIssue(h IssueHandler, actions ...string) {
+	d.register(issueEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*IssueEvent))
+	}, actions)
+}
+
+// OnNote registers h for "Note Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnNote(h NoteHandler, actions ...string) {
+	d.register(noteEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*NoteEvent))
+	}, actions)
+}
+
+// OnPush registers h for "Push Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnPush(h PushHandler, actions ...string) {
+	d.register(pushEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*PushEvent))
+	}, actions)
+}
+
+// OnTagPush registers h for "Tag Push Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnTagPush(h TagPushHandler, actions ...string) {
+	d.register(tagPushEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*TagPushEvent))
+	}, actions)
+}
+
+// OnRelease registers h for "Release Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnRelease(h ReleaseHandler, actions ...string) {
+	d.register(releaseEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*ReleaseEvent))
+	}, actions)
+}
+
+// OnPipeline registers h for "Pipeline Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnPipeline(h PipelineHandler, actions ...string) {
+	d.register(pipelineEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*PipelineEvent))
+	}, actions)
+}
+
+// OnJob registers h for "Job Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnJob(h JobHandler, actions ...string) {
+	d.register(jobEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*JobEvent))
+	}, actions)
+}
+
+// OnWikiPage registers h for "Wiki Page Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnWikiPage(h WikiPageHandler, actions ...string) {
+	d.register(wikiPageEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*WikiPageEvent))
+	}, actions)
+}
+
+// OnMember registers h for "Member Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnMember(h MemberHandler, actions ...string) {
+	d.register(memberEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*MemberEvent))
+	}, actions)
+}
+
+// OnRepository registers h for "Repository Update Hook" events, optionally filtered by action.
+func (d *Dispatcher) OnRepository(h RepositoryHandler, actions ...string) {
+	d.register(repositoryEvent, func(ctx context.Context, e Event) error {
+		return h(ctx, e.(*RepositoryEvent))
+	}, actions)
+}
+
+// OnEvent registers h for events of eventType, such as "Push Hook", optionally filtered by action.
+// Handlers registered with OnEvent and the typed On* methods are called in registration order.
+func (d *Dispatcher) OnEvent(eventType string, h EventHandler, actions ...string) {
+	d.register(eventType, func(ctx context.Context, e Event) error {
+		return h(ctx, e)
+	}, actions)
+}
+
+// OnMatch registers h for the events of any type matched by f. With a Queue set, f is
+// evaluated both when the delivery is received and when the job is handled.
+// Nothing is registered when f is invalid, see Filter.Validate.
+func (d *Dispatcher) OnMatch(f *Filter, h EventHandler) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	rt := &route{filter: f, handle: h}
+	kinds := eventKinds
+	if f != nil && len(f.Kinds) > 0 {
+		kinds = f.Kinds
+	}
+
+	d.mu.Lock()
+	for _, kind := range kinds {
+		// a kind listed twice must not call h twice
+		eventType := kindEventTypes[kind]
+		if !slices.Contains(d.routes[eventType], rt) {
+			d.routes[eventType] = append(d.routes[eventType], rt)
+		}
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dispatcher) register(eventType string, handle func(context.Context, Event) error, actions []string) {
+	rt := &route{handle: handle}
+	if len(actions) > 0 {
+		rt.actions = make(map[string]struct{}, len(actions))
+		for _, action := range actions {
+			rt.actions[action] = struct{}{}
+		}
+	}
+
+	d.mu.Lock()
+	d.routes[eventType] = append(d.routes[eventType], rt)
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := d.auth.config()
+	if err, unwritten := auth.Auth(w, r); err != nil {
+		var ae *AuthError
+		if unwritten && errors.As(err, &ae) {
+			http.Error(w, ae.Message, ae.StatusCode)
+		}
+		return
+	}
+
+	payload := payloadBytes(auth.GetPayload())
+	event, err := decodeEvent(auth.GetEventType(), payload, d.unknown)
+	if d.observer != nil {
+		d.observer.Parsed(r.Context(), auth.GetEventType(), auth.GetEventGUID(), err)
+	}
+	if errors.Is(err, ErrUnknownEventType) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, payloadParseErrorMessage, http.StatusBadRequest)
+		return
+	}
+
+	routes, err := d.match(r.Context(), auth.GetEventType(), event)
+	if err != nil {
+		_ = auth.ForgetDelivery()
+		http.Error(w, filterErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	if len(routes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	delivery := Delivery{
+		EventType:  auth.GetEventType(),
+		GUID:       auth.GetEventGUID(),
+		Payload:    payload,
+		Redelivery: auth.IsRedelivery(),
+	}
+
+	d.mu.RLock()
+	q := d.queue
+	d.mu.RUnlock()
+	if q != nil {
+		err := q.Enqueue(r.Context(), &Job{Delivery: delivery, Event: event})
+		if err != nil {
+			_ = auth.ForgetDelivery()
+		}
+		switch {
+		case errors.Is(err, ErrQueueFull):
+			http.Error(w, queueFullErrorMessage, http.StatusServiceUnavailable)
+		case errors.Is(err, ErrQueueClosed):
+			http.Error(w, queueClosedErrorMessage, http.StatusServiceUnavailable)
+		case err != nil:
+			http.Error(w, enqueueErrorMessage, http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return
+	}
+
+	if err := d.run(r.Context(), &delivery, event, routes); err != nil {
+		_ = auth.ForgetDelivery()
+		http.Error(w, handlerErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handle calls the handlers matching a queued job. Handlers already called for the job
+// are called again when the job is retried, so they should be idempotent.
+func (d *Dispatcher) Handle(ctx context.Context, job *Job) error {
+	event := job.Event
+	if event == nil {
+		var err error
+		event, err = decodeEvent(job.Delivery.EventType, job.Delivery.Payload, d.unknown)
+		if d.observer != nil {
+			d.observer.Parsed(ctx, job.Delivery.EventType, job.Delivery.GUID, err)
+		}
+		if err != nil {
+			return err
+		}
+		job.Event = event
+	}
+
+	delivery := job.Delivery
+	routes, err := d.match(ctx, delivery.EventType, event)
+	if err != nil {
+		return err
+	}
+	return d.run(ctx, &delivery, event, routes)
+}
+
+func (d *Dispatcher) run(ctx context.Context, delivery *Delivery, event Event, routes []*route) (err error) {
+	if d.observer != nil {
+		start := time.Now()
+		// a panicking handler is reported as failed, the panic is left to the caller, such
+		// as WorkerPool or net/http, to recover from
+		defer func() {
+			if r := recover(); r != nil {
+				d.observer.Handled(ctx, delivery, time.Since(start), fmt.Errorf("%w: %v", ErrHandlerPanic, r))
+				panic(r)
+			}
+			d.observer.Handled(ctx, delivery, time.Since(start), err)
+		}()
+	}
+
+	ctx = context.WithValue(ctx, deliveryContextKey{}, delivery)
+	for _, rt := range routes {
+		if err := rt.handle(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) match(ctx context.Context, eventType string, event Event) ([]*route, error) {
+	d.mu.RLock()
+	routes := d.routes[eventType]
+	resolver := d.resolver
+	d.mu.RUnlock()
+
+	var matched []*route
+	for _, rt := range routes {
+		ok, err := rt.match(ctx, event, resolver)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, rt)
+		}
+	}
+	return matched, nil
+}
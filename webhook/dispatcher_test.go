@@ -0,0 +1,226 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const dispatcherSignKey = "1234"
+
+func newDispatcherRequest(t *testing.T, eventType string, payload []byte) *http.Request {
+	mac := hmac.New(sha256.New, []byte(dispatcherSignKey))
+	mac.Write(payload)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/hook", bytes.NewReader(payload))
+	req.Header.Set(headerUserAgent, headerUserAgentValue)
+	req.Header.Set(headerContentTypeName, headerContentTypeJsonValue)
+	req.Header.Set(headerEventType, eventType)
+	req.Header.Set(headerEventGUID, "delivery-1")
+	req.Header.Set(headerEventToken, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestNewDispatcher(t *testing.T) {
+	d, err := NewDispatcher(nil)
+	assert.Equal(t, (*Dispatcher)(nil), d)
+	assert.Equal(t, errorNilToken, err)
+}
+
+func TestDispatcherServeHTTP(t *testing.T) {
+	d, err := NewDispatcher([]byte(dispatcherSignKey))
+	assert.Equal(t, nil, err)
+
+	var prCalls, openCalls, noteCalls int
+	d.OnPullRequest(func(ctx context.Context, e *PullRequestEvent) error {
+		prCalls++
+		assert.Equal(t, "4", *e.GetNumber())
+
+		delivery, ok := DeliveryFromContext(ctx)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, "delivery-1", delivery.GUID)
+		assert.Equal(t, pullRequestEvent, delivery.EventType)
+		return nil
+	})
+	d.OnPullRequest(func(ctx context.Context, e *PullRequestEvent) error {
+		openCalls++
+		return nil
+	}, "open", "reopen")
+	d.OnPullRequest(func(ctx context.Context, e *PullRequestEvent) error {
+		t.Error("handler filtered by action should not be called")
+		return nil
+	}, "merge")
+	d.OnNote(func(ctx context.Context, e *NoteEvent) error {
+		noteCalls++
+		return errors.New("failed")
+	})
+
+	pr := readWebHookTestdata(t, webhookTestDataDir+"pr_create.json", nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pullRequestEvent, pr))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, prCalls)
+	assert.Equal(t, 1, openCalls)
+
+	note := readWebHookTestdata(t, webhookTestDataDir+"pr_note.json", nil)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, noteEvent, note))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, noteCalls)
+
+	issue := readWebHookTestdata(t, webhookTestDataDir+"issues_create.json", nil)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, issueEvent, issue))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, "Dummy Hook", []byte("{}")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pullRequestEvent, []byte("{\"object_attributes\": 1}")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a request without body signed over the empty payload
+	req := newDispatcherRequest(t, pullRequestEvent, nil)
+	req.Body = nil
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = newDispatcherRequest(t, "Empty Hook", nil)
+	req.Body = nil
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	req = newDispatcherRequest(t, pullRequestEvent, pr)
+	req.Header.Set(headerUserAgent, "curl/8.0")
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = newDispatcherRequest(t, pullRequestEvent, pr)
+	req.Header.Set(headerEventToken, "sha256=00")
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, 1, prCalls)
+}
+
+func TestNewDispatcherWithAuth(t *testing.T) {
+	a := GitCodeAuthentication{}
+	_ = a.SetSignKeys([]byte("old-secret"), []byte(dispatcherSignKey))
+
+	d := NewDispatcherWithAuth(&a)
+	calls := 0
+	d.OnPush(func(ctx context.Context, e *PushEvent) error {
+		calls++
+		return nil
+	})
+
+	push := readWebHookTestdata(t, webhookTestDataDir+"push_code.json", nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, -1, a.GetMatchedKeyIndex())
+}
+
+func TestDispatcherMoreEvents(t *testing.T) {
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+
+	var releases, wikis int
+	d.OnRelease(func(ctx context.Context, e *ReleaseEvent) error {
+		releases++
+		assert.Equal(t, "v1.0.0", *e.Tag)
+		return nil
+	}, "create")
+	d.OnWikiPage(func(ctx context.Context, e *WikiPageEvent) error {
+		wikis++
+		return nil
+	}, "create")
+
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, readWebHookTestdata(t, webhookTestDataDir+"release.json", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, releases)
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, wikiPageEvent, readWebHookTestdata(t, webhookTestDataDir+"wiki_page.json", nil)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 0, wikis)
+}
+
+func TestDispatcherOnEvent(t *testing.T) {
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+
+	var order []string
+	d.OnEvent(releaseEvent, func(ctx context.Context, e Event) error {
+		order = append(order, "generic:"+*e.GetRepo())
+		return nil
+	})
+	d.OnRelease(func(ctx context.Context, e *ReleaseEvent) error {
+		order = append(order, "typed")
+		return nil
+	})
+	d.OnEvent(releaseEvent, func(ctx context.Context, e Event) error {
+		order = append(order, "delete")
+		return nil
+	}, "delete")
+
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, readWebHookTestdata(t, webhookTestDataDir+"release.json", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"generic:test1", "typed"}, order)
+}
+
+func TestDispatcherRetryAfterFailure(t *testing.T) {
+	a := GitCodeAuthentication{}
+	_ = a.SetSignKey([]byte(dispatcherSignKey))
+	a.SetDeliveryStore(NewMemoryDeliveryStore(10, 0), DuplicateDeliveryReject)
+	d := NewDispatcherWithAuth(&a)
+
+	fail := true
+	d.OnPush(func(ctx context.Context, e *PushEvent) error {
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	push := readWebHookTestdata(t, webhookTestDataDir+"push_code.json", nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// GitCode retries with the same delivery ID, which must not be rejected as a duplicate
+	fail = false
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,270 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"github.com/opensourceways/go-gitcode/openapi"
+	"strconv"
+	"time"
+)
+
+// EventKind is the short, header independent name of an event type.
+type EventKind string
+
+const (
+	EventKindPush        EventKind = "push"
+	EventKindTagPush     EventKind = "tag_push"
+	EventKindIssue       EventKind = "issue"
+	EventKindPullRequest EventKind = "pull_request"
+	EventKindNote        EventKind = "note"
+	EventKindRelease     EventKind = "release"
+	EventKindPipeline    EventKind = "pipeline"
+	EventKindJob         EventKind = "job"
+	EventKindWikiPage    EventKind = "wiki_page"
+	EventKindMember      EventKind = "member"
+	EventKindRepository  EventKind = "repository"
+)
+
+// TargetKind is the kind of object an event is about.
+type TargetKind string
+
+const (
+	TargetNone        TargetKind = ""
+	TargetIssue       TargetKind = "issue"
+	TargetPullRequest TargetKind = "pull_request"
+	TargetCommit      TargetKind = "commit"
+)
+
+// Envelope holds the fields shared by all events in a normalized form, so routing and
+// logging code can handle every event type the same way. Fields the event does not
+// carry are left empty.
+type Envelope struct {
+	Kind   EventKind
+	Action string
+	Org    string
+	Repo   string
+	// RepoPath is the path with namespace, such as "org/repo".
+	RepoPath string
+	RepoURL  string
+
+	Target TargetKind
+	// Number is the issue or pull request number, 0 for other targets.
+	Number int
+	// SHA is the commit the event refers to, such as the pushed head or the last
+	// commit of a pull request.
+	SHA string
+	// Ref is the pushed branch or tag, or the target branch of a pull request.
+	Ref string
+	// URL is the web page of the target, falling back to the repository.
+	URL string
+
+	// Actor is the login of the user who triggered the event.
+	Actor     string
+	CommentID string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NewEnvelope derives the Envelope of e. It returns nil when e is nil.
+func NewEnvelope(e Event) *Envelope {
+	if e == nil {
+		return nil
+	}
+
+	env := &Envelope{
+		Kind:   EventKindOf(e),
+		Action: deref(e.GetAction()),
+		Org:    deref(e.GetOrg()),
+		Repo:   deref(e.GetRepo()),
+		Ref:    deref(e.GetBase()),
+		URL:    deref(e.GetHtmlURL()),
+	}
+	if n := e.GetNumber(); n != nil {
+		env.Number, _ = strconv.Atoi(*n)
+	}
+
+	switch v := e.(type) {
+	case *IssueEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetIssue
+		env.Actor = login(v.User)
+		env.setAttributesTime(v.Attributes)
+	case *PullRequestEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetPullRequest
+		env.SHA = deref(v.GetHeadSHA())
+		env.Actor = login(v.User)
+		env.setAttributesTime(v.Attributes)
+	case *NoteEvent:
+		env.setProject(v.Repository)
+		switch {
+		case v.PR != nil:
+			env.Target = TargetPullRequest
+		case v.Issue != nil:
+			env.Target = TargetIssue
+		case v.Attributes != nil && v.Attributes.CommentKind != nil && *v.Attributes.CommentKind == "Commit":
+			env.Target = TargetCommit
+		}
+		if v.PR != nil && v.PR.LastCommit != nil {
+			env.SHA = deref(v.PR.LastCommit.ID)
+		}
+		env.Actor = login(v.User)
+		env.CommentID = deref(v.GetCommentID())
+		env.setAttributesTime(v.Attributes)
+	case *PushEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetCommit
+		env.SHA = deref(v.After)
+		env.Actor = deref(v.Author)
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.UpdatedTime)
+	case *TagPushEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetCommit
+		env.SHA = deref(v.After)
+		env.Actor = deref(v.Author)
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.UpdatedTime)
+	case *ReleaseEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetCommit
+		env.SHA = deref(v.GetHead())
+		env.Actor = login(v.User)
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.ReleaseTime)
+	case *PipelineEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetCommit
+		if v.MergeRequest != nil {
+			env.Target = TargetPullRequest
+		}
+		env.Actor = login(v.User)
+		if v.Attributes != nil {
+			env.SHA = deref(v.Attributes.SHA)
+			env.CreatedAt = timeOf(v.Attributes.CreateTime)
+			env.UpdatedAt = timeOf(v.Attributes.FinishTime)
+		}
+	case *JobEvent:
+		env.setProject(v.Repository)
+		env.Target = TargetCommit
+		env.SHA = deref(v.SHA)
+		env.Actor = login(v.User)
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.FinishTime)
+	case *WikiPageEvent:
+		env.setProject(v.Repository)
+		env.Actor = login(v.User)
+		if v.Attributes != nil {
+			env.CreatedAt = timeOf(v.Attributes.CreateTime)
+			env.UpdatedAt = timeOf(v.Attributes.UpdatedTime)
+		}
+	case *MemberEvent:
+		// user_username is the member, GitCode does not send who changed the membership.
+		env.setProject(v.Repository)
+		if env.RepoPath == "" {
+			env.RepoPath = deref(v.ProjectPath)
+		}
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.UpdatedTime)
+	case *RepositoryEvent:
+		env.setProject(v.Repository)
+		env.Actor = deref(v.GetAuthor())
+		env.CreatedAt = timeOf(v.CreateTime)
+		env.UpdatedAt = timeOf(v.UpdatedTime)
+	}
+
+	if env.URL == "" {
+		env.URL = env.RepoURL
+	}
+	return env
+}
+
+// FullName returns "org/repo", or RepoPath when the org or repo is unknown.
+func (env *Envelope) FullName() string {
+	if env.Org == "" || env.Repo == "" {
+		return env.RepoPath
+	}
+	return env.Org + "/" + env.Repo
+}
+
+// EventKindOf returns the kind of e, or an empty kind for event types this package does not define.
+func EventKindOf(e Event) EventKind {
+	switch e.(type) {
+	case *PushEvent:
+		return EventKindPush
+	case *TagPushEvent:
+		return EventKindTagPush
+	case *IssueEvent:
+		return EventKindIssue
+	case *PullRequestEvent:
+		return EventKindPullRequest
+	case *NoteEvent:
+		return EventKindNote
+	case *ReleaseEvent:
+		return EventKindRelease
+	case *PipelineEvent:
+		return EventKindPipeline
+	case *JobEvent:
+		return EventKindJob
+	case *WikiPageEvent:
+		return EventKindWikiPage
+	case *MemberEvent:
+		return EventKindMember
+	case *RepositoryEvent:
+		return EventKindRepository
+	default:
+		return ""
+	}
+}
+
+func (env *Envelope) setProject(p *Project) {
+	if p == nil {
+		return
+	}
+	env.RepoPath = deref(p.Path)
+	env.RepoURL = deref(p.HTMLURL)
+}
+
+func (env *Envelope) setAttributesTime(a *Attributes) {
+	if a == nil {
+		return
+	}
+	env.CreatedAt = timeOf(a.CreateTime)
+	env.UpdatedAt = timeOf(a.UpdatedTime)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func login(u *openapi.User) string {
+	if u == nil {
+		return ""
+	}
+	if u.UserName != nil {
+		return *u.UserName
+	}
+	return deref(u.Login)
+}
+
+func timeOf(t *openapi.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Time(*t)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewEnvelope(t *testing.T) {
+	decode := func(file, eventType string) Event {
+		data := readWebHookTestdata(t, webhookTestDataDir+file, nil)
+		e, err := decodeEvent(eventType, data, nil)
+		assert.Equal(t, nil, err)
+		return e
+	}
+
+	assert.Nil(t, NewEnvelope(nil))
+
+	env := NewEnvelope(decode("issues_create.json", issueEvent))
+	assert.Equal(t, EventKindIssue, env.Kind)
+	assert.Equal(t, TargetIssue, env.Target)
+	assert.Equal(t, "open", env.Action)
+	assert.Equal(t, "ibforuorg/test1", env.FullName())
+	assert.Equal(t, 4, env.Number)
+	assert.Equal(t, "https://gitcode.com/ibforuorg/test1/issues/4", env.URL)
+	assert.Equal(t, "https://gitcode.com/ibforuorg/test1", env.RepoURL)
+	assert.Equal(t, false, env.CreatedAt.IsZero())
+
+	env = NewEnvelope(decode("pr_update.json", pullRequestEvent))
+	assert.Equal(t, EventKindPullRequest, env.Kind)
+	assert.Equal(t, TargetPullRequest, env.Target)
+	assert.Equal(t, "update", env.Action)
+	assert.Equal(t, "56785678", env.SHA)
+	assert.Equal(t, "main", env.Ref)
+	assert.Equal(t, true, env.UpdatedAt.After(env.CreatedAt))
+
+	env = NewEnvelope(decode("pr_note.json", noteEvent))
+	assert.Equal(t, EventKindNote, env.Kind)
+	assert.Equal(t, TargetPullRequest, env.Target)
+	assert.Equal(t, 4, env.Number)
+	assert.Equal(t, "71e9657489bcddbed4c0a9d2b1e29eb7c8ab26c3", env.CommentID)
+
+	env = NewEnvelope(decode("issues_note.json", noteEvent))
+	assert.Equal(t, TargetIssue, env.Target)
+	assert.Equal(t, 4, env.Number)
+
+	env = NewEnvelope(decode("push_code.json", pushEvent))
+	assert.Equal(t, EventKindPush, env.Kind)
+	assert.Equal(t, TargetCommit, env.Target)
+	assert.Equal(t, "2f03691536b8ef5ee2710b8605b9d16ccc96b52f", env.SHA)
+	assert.Equal(t, "dev", env.Ref)
+	assert.Equal(t, "ibforu", env.Actor)
+	assert.Equal(t, 0, env.Number)
+	// pushes have no page of their own
+	assert.Equal(t, env.RepoURL, env.URL)
+
+	env = NewEnvelope(decode("tag_push.json", tagPushEvent))
+	assert.Equal(t, EventKindTagPush, env.Kind)
+	assert.Equal(t, "v1.0.0", env.Ref)
+
+	env = NewEnvelope(decode("release.json", releaseEvent))
+	assert.Equal(t, EventKindRelease, env.Kind)
+	assert.Equal(t, "ibforu", env.Actor)
+	assert.Equal(t, time.Date(2024, 11, 9, 2, 0, 5, 0, time.UTC), env.UpdatedAt.UTC())
+
+	env = NewEnvelope(decode("pipeline.json", pipelineEvent))
+	assert.Equal(t, TargetPullRequest, env.Target)
+	assert.Equal(t, 7, env.Number)
+
+	env = NewEnvelope(decode("member.json", memberEvent))
+	assert.Equal(t, EventKindMember, env.Kind)
+	assert.Equal(t, TargetNone, env.Target)
+	assert.Equal(t, "", env.Actor)
+
+	for _, e := range []Event{
+		new(IssueEvent), new(PullRequestEvent), new(NoteEvent), new(PushEvent), new(TagPushEvent),
+		new(ReleaseEvent), new(PipelineEvent), new(JobEvent), new(WikiPageEvent), new(MemberEvent), new(RepositoryEvent),
+	} {
+		env := NewEnvelope(e)
+		assert.NotEqual(t, EventKind(""), env.Kind)
+		assert.Equal(t, "", env.FullName())
+	}
+}
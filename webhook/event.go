@@ -0,0 +1,82 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+// Event is implemented by every webhook payload decoded by this package, so middleware
+// can read the common fields without switching on the concrete event type.
+// Getters return nil when the payload does not carry the field.
+type Event interface {
+	GetAction() *string
+	GetActionDetail() *string
+	GetState() *string
+	GetOrg() *string
+	GetRepo() *string
+	GetHtmlURL() *string
+	GetBase() *string
+	GetHead() *string
+	GetNumber() *string
+	GetID() *string
+	GetAuthor() *string
+	GetCommentID() *string
+	GetCommentKind() *string
+	GetComment() *string
+	GetCommenter() *string
+	GetCreateTime() *string
+	GetUpdateTime() *string
+}
+
+var (
+	_ Event = (*IssueEvent)(nil)
+	_ Event = (*PullRequestEvent)(nil)
+	_ Event = (*NoteEvent)(nil)
+	_ Event = (*PushEvent)(nil)
+	_ Event = (*TagPushEvent)(nil)
+	_ Event = (*ReleaseEvent)(nil)
+	_ Event = (*PipelineEvent)(nil)
+	_ Event = (*JobEvent)(nil)
+	_ Event = (*WikiPageEvent)(nil)
+	_ Event = (*MemberEvent)(nil)
+	_ Event = (*RepositoryEvent)(nil)
+)
+
+// EventTypeOf returns the X-GitCode-Event header value GitCode sends with e,
+// or an empty string for event types this package does not define.
+func EventTypeOf(e Event) string {
+	switch e.(type) {
+	case *PushEvent:
+		return EventTypePush
+	case *IssueEvent:
+		return EventTypeIssue
+	case *PullRequestEvent:
+		return EventTypePullRequest
+	case *NoteEvent:
+		return EventTypeNote
+	case *TagPushEvent:
+		return EventTypeTagPush
+	case *ReleaseEvent:
+		return EventTypeRelease
+	case *PipelineEvent:
+		return EventTypePipeline
+	case *JobEvent:
+		return EventTypeJob
+	case *WikiPageEvent:
+		return EventTypeWikiPage
+	case *MemberEvent:
+		return EventTypeMember
+	case *RepositoryEvent:
+		return EventTypeRepository
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/opensourceways/go-gitcode/openapi"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ErrNoAssociationResolver is returned by Filter.Match when the filter checks the author
+// association but no AssociationResolver is given.
+var ErrNoAssociationResolver = errors.New("filter checks author association but no resolver is configured")
+
+// Association is the access level of the event actor on the repository.
+type Association string
+
+const (
+	AssociationAdmin Association = "admin"
+	AssociationWrite Association = "write"
+	AssociationRead  Association = "read"
+	// AssociationNone is used for users that are not members of the repository.
+	AssociationNone Association = "none"
+)
+
+// AssociationResolver looks up the association of login with the repository org/repo.
+type AssociationResolver interface {
+	Association(ctx context.Context, org, repo, login string) (Association, error)
+}
+
+// AssociationResolverFunc adapts a function to an AssociationResolver.
+type AssociationResolverFunc func(ctx context.Context, org, repo, login string) (Association, error)
+
+func (f AssociationResolverFunc) Association(ctx context.Context, org, repo, login string) (Association, error) {
+	return f(ctx, org, repo, login)
+}
+
+// MemberPermissionGetter is implemented by *openapi.RepositoryService.
+type MemberPermissionGetter interface {
+	GetRepoMemberPermission(ctx context.Context, owner, repo, login string) (*openapi.User, [2]bool, error)
+}
+
+var _ MemberPermissionGetter = (*openapi.RepositoryService)(nil)
+
+// NewPermissionResolver returns an AssociationResolver that asks GitCode for the
+// repository permission of the user, see LookupAssociation.
+func NewPermissionResolver(g MemberPermissionGetter) AssociationResolver {
+	return AssociationResolverFunc(func(ctx context.Context, org, repo, login string) (Association, error) {
+		return LookupAssociation(ctx, g, org, repo, login)
+	})
+}
+
+// LookupAssociation asks g for the permission of login on org/repo. Users that are not
+// members of the repository are AssociationNone.
+func LookupAssociation(ctx context.Context, g MemberPermissionGetter, org, repo, login string) (Association, error) {
+	user, status, err := g.GetRepoMemberPermission(ctx, org, repo, login)
+	if status[1] {
+		// not a member of the repository
+		return AssociationNone, nil
+	}
+	if err != nil {
+		return AssociationNone, err
+	}
+	if user == nil {
+		return AssociationNone, nil
+	}
+	if user.Permissions != nil && user.Permissions.Admin != nil && *user.Permissions.Admin {
+		return AssociationAdmin, nil
+	}
+	return ParseAssociation(deref(user.Permission)), nil
+}
+
+// ParseAssociation maps a permission returned by GetRepoMemberPermission, or a GitCode
+// role name such as "maintainer" or "developer", to an Association.
+func ParseAssociation(s string) Association {
+	switch strings.ToLower(s) {
+	case "admin", "owner", "maintain", "maintainer":
+		return AssociationAdmin
+	case "write", "push", "developer":
+		return AssociationWrite
+	case "read", "pull", "reporter", "guest":
+		return AssociationRead
+	default:
+		return AssociationNone
+	}
+}
+
+// Filter selects events. All conditions that are set must hold, an empty Filter matches
+// every event. Conditions on lists hold when any of the values matches, except Labels
+// which requires every label.
+//
+// Repos and Branches are patterns in the syntax of path.Match, matched against "org/repo"
+// and Envelope.Ref, so "org/*" and "release/*" work as expected.
+//
+// A Filter can be written in YAML:
+//
+//	kinds: [pull_request]
+//	actions: [open, reopen]
+//	repos: ["org-x/*"]
+//	branches: ["release/*"]
+//	not:
+//	  labels: [wip]
+type Filter struct {
+	Kinds         []EventKind   `yaml:"kinds,omitempty"`
+	Actions       []string      `yaml:"actions,omitempty"`
+	Repos         []string      `yaml:"repos,omitempty"`
+	Branches      []string      `yaml:"branches,omitempty"`
+	Labels        []string      `yaml:"labels,omitempty"`
+	ExcludeLabels []string      `yaml:"exclude_labels,omitempty"`
+	Associations  []Association `yaml:"associations,omitempty"`
+
+	All []*Filter `yaml:"all,omitempty"`
+	Any []*Filter `yaml:"any,omitempty"`
+	Not *Filter   `yaml:"not,omitempty"`
+}
+
+// FilterAll returns a Filter matching events matched by all of filters.
+func FilterAll(filters ...*Filter) *Filter {
+	return &Filter{All: filters}
+}
+
+// FilterAny returns a Filter matching events matched by at least one of filters.
+func FilterAny(filters ...*Filter) *Filter {
+	return &Filter{Any: filters}
+}
+
+// FilterNot returns a Filter matching events not matched by f.
+func FilterNot(f *Filter) *Filter {
+	return &Filter{Not: f}
+}
+
+// ParseFilter decodes a Filter from YAML and validates it. Unknown keys are rejected.
+func ParseFilter(data []byte) (*Filter, error) {
+	f := new(Filter)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse filter: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Validate reports unknown kinds and associations and malformed patterns.
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	for _, k := range f.Kinds {
+		if !slices.Contains(eventKinds, k) {
+			return fmt.Errorf("unknown event kind %q", k)
+		}
+	}
+	for _, a := range f.Associations {
+		switch a {
+		case AssociationAdmin, AssociationWrite, AssociationRead, AssociationNone:
+		default:
+			return fmt.Errorf("unknown association %q", a)
+		}
+	}
+	for _, p := range append(slices.Clip(f.Repos), f.Branches...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	for _, sub := range append(append(slices.Clip(f.All), f.Any...), f.Not) {
+		if err := sub.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match reports whether e matches f. r is only used, and required, when the filter
+// checks Associations; the actor is the commenter for notes.
+func (f *Filter) Match(ctx context.Context, e Event, r AssociationResolver) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	if e == nil {
+		return false, nil
+	}
+	return f.match(ctx, e, NewEnvelope(e), r)
+}
+
+func (f *Filter) match(ctx context.Context, e Event, env *Envelope, r AssociationResolver) (bool, error) {
+	if !f.matchEnvelope(e, env) {
+		return false, nil
+	}
+
+	if len(f.Associations) > 0 {
+		ok, err := f.matchAssociation(ctx, env, r)
+		if !ok || err != nil {
+			return false, err
+		}
+	}
+
+	for _, sub := range f.All {
+		if ok, err := sub.match(ctx, e, env, r); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	if len(f.Any) > 0 {
+		matched := false
+		for _, sub := range f.Any {
+			ok, err := sub.match(ctx, e, env, r)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if f.Not != nil {
+		ok, err := f.Not.match(ctx, e, env, r)
+		return !ok && err == nil, err
+	}
+	return true, nil
+}
+
+func (f *Filter) matchEnvelope(e Event, env *Envelope) bool {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, env.Kind) {
+		return false
+	}
+	if len(f.Actions) > 0 && !slices.Contains(f.Actions, env.Action) {
+		return false
+	}
+	if len(f.Repos) > 0 && !matchPattern(f.Repos, env.FullName()) {
+		return false
+	}
+	if len(f.Branches) > 0 && !matchPattern(f.Branches, env.Ref) {
+		return false
+	}
+
+	if len(f.Labels) > 0 || len(f.ExcludeLabels) > 0 {
+		labels := eventLabels(e)
+		for _, l := range f.Labels {
+			if !slices.Contains(labels, l) {
+				return false
+			}
+		}
+		for _, l := range f.ExcludeLabels {
+			if slices.Contains(labels, l) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchAssociation never matches events without an actor, whose association is unknown.
+func (f *Filter) matchAssociation(ctx context.Context, env *Envelope, r AssociationResolver) (bool, error) {
+	if env.Actor == "" {
+		return false, nil
+	}
+	if r == nil {
+		return false, ErrNoAssociationResolver
+	}
+
+	assoc, err := r.Association(ctx, env.Org, env.Repo, env.Actor)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(f.Associations, assoc), nil
+}
+
+func matchPattern(patterns []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func eventLabels(e Event) []string {
+	var labels []*openapi.Label
+	switch v := e.(type) {
+	case *IssueEvent:
+		labels = v.Labels
+	case *PullRequestEvent:
+		labels = v.Labels
+	case *NoteEvent:
+		labels = v.Labels
+	}
+
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l != nil {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+var eventKinds = []EventKind{
+	EventKindPush, EventKindTagPush, EventKindIssue, EventKindPullRequest, EventKindNote, EventKindRelease,
+	EventKindPipeline, EventKindJob, EventKindWikiPage, EventKindMember, EventKindRepository,
+}
+
+var kindEventTypes = map[EventKind]string{
+	EventKindPush:        EventTypePush,
+	EventKindTagPush:     EventTypeTagPush,
+	EventKindIssue:       EventTypeIssue,
+	EventKindPullRequest: EventTypePullRequest,
+	EventKindNote:        EventTypeNote,
+	EventKindRelease:     EventTypeRelease,
+	EventKindPipeline:    EventTypePipeline,
+	EventKindJob:         EventTypeJob,
+	EventKindWikiPage:    EventTypeWikiPage,
+	EventKindMember:      EventTypeMember,
+	EventKindRepository:  EventTypeRepository,
+}
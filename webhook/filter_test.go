@@ -0,0 +1,257 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func filterString(s string) *string {
+	return &s
+}
+
+func newFilterPullRequest(action, branch string, labels ...string) *PullRequestEvent {
+	org, repo, path, user, number := "org-x", "bot", "org-x/bot", "alice", 3
+	e := &PullRequestEvent{
+		Attributes: &Attributes{Action: &action, TargetBranch: &branch, Number: &number},
+		User:       &openapi.User{UserName: &user},
+		Repository: &Project{Namespace: &org, Name: &repo, Path: &path},
+	}
+	for _, l := range labels {
+		e.Labels = append(e.Labels, &openapi.Label{Name: l})
+	}
+	return e
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter([]byte(`
+kinds: [pull_request]
+actions: [open, reopen]
+repos: ["org-x/*"]
+branches: ["release/*"]
+not:
+  labels: [wip]
+`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []EventKind{EventKindPullRequest}, f.Kinds)
+	assert.Equal(t, []string{"wip"}, f.Not.Labels)
+
+	f, err = ParseFilter(nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &Filter{}, f)
+
+	for _, data := range []string{
+		"kinds: [pr]",
+		"associations: [owner]",
+		"repos: ['org/[']",
+		"any: [{branches: ['[']}]",
+		"unknown: 1",
+		"kinds: push",
+	} {
+		_, err = ParseFilter([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	ctx := context.Background()
+	f := &Filter{
+		Kinds:    []EventKind{EventKindPullRequest},
+		Actions:  []string{"open", "reopen"},
+		Repos:    []string{"org-x/*"},
+		Branches: []string{"release/*"},
+		Not:      &Filter{Labels: []string{"wip"}},
+	}
+
+	testCases := []struct {
+		e    Event
+		want bool
+	}{
+		{newFilterPullRequest("open", "release/1.0"), true},
+		{newFilterPullRequest("reopen", "release/2.0", "kind/bug"), true},
+		{newFilterPullRequest("open", "release/1.0", "wip"), false},
+		{newFilterPullRequest("merge", "release/1.0"), false},
+		{newFilterPullRequest("open", "main"), false},
+		{&IssueEvent{Attributes: &Attributes{Action: new(string)}}, false},
+		{nil, false},
+	}
+	for i, tc := range testCases {
+		ok, err := f.Match(ctx, tc.e, nil)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tc.want, ok, i)
+	}
+
+	ok, _ := (*Filter)(nil).Match(ctx, new(PushEvent), nil)
+	assert.Equal(t, true, ok)
+	ok, _ = new(Filter).Match(ctx, new(PushEvent), nil)
+	assert.Equal(t, true, ok)
+
+	anyOf := FilterAny(&Filter{Labels: []string{"a", "b"}}, &Filter{ExcludeLabels: []string{"c"}})
+	ok, _ = anyOf.Match(ctx, newFilterPullRequest("open", "main", "b", "a", "c"), nil)
+	assert.Equal(t, true, ok)
+	ok, _ = anyOf.Match(ctx, newFilterPullRequest("open", "main", "a", "c"), nil)
+	assert.Equal(t, false, ok)
+
+	allOf := FilterAll(&Filter{Actions: []string{"open"}}, FilterNot(&Filter{Branches: []string{"main"}}))
+	ok, _ = allOf.Match(ctx, newFilterPullRequest("open", "dev"), nil)
+	assert.Equal(t, true, ok)
+	ok, _ = allOf.Match(ctx, newFilterPullRequest("open", "main"), nil)
+	assert.Equal(t, false, ok)
+}
+
+func TestFilterMatchAssociation(t *testing.T) {
+	ctx := context.Background()
+	nonMembers := &Filter{Kinds: []EventKind{EventKindPullRequest}, Associations: []Association{AssociationNone}}
+
+	_, err := nonMembers.Match(ctx, newFilterPullRequest("open", "main"), nil)
+	assert.Equal(t, ErrNoAssociationResolver, err)
+
+	calls := 0
+	resolver := AssociationResolverFunc(func(ctx context.Context, org, repo, login string) (Association, error) {
+		calls++
+		assert.Equal(t, "org-x", org)
+		assert.Equal(t, "bot", repo)
+		if login == "alice" {
+			return AssociationWrite, nil
+		}
+		return AssociationNone, nil
+	})
+
+	ok, err := nonMembers.Match(ctx, newFilterPullRequest("open", "main"), resolver)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, ok)
+
+	e := newFilterPullRequest("open", "main")
+	e.User.UserName = filterString("bob")
+	ok, _ = nonMembers.Match(ctx, e, resolver)
+	assert.Equal(t, true, ok)
+
+	// the resolver is not called when cheaper conditions already fail
+	ok, _ = nonMembers.Match(ctx, new(PushEvent), resolver)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 2, calls)
+
+	failing := AssociationResolverFunc(func(ctx context.Context, org, repo, login string) (Association, error) {
+		return "", errors.New("failed")
+	})
+	_, err = nonMembers.Match(ctx, e, failing)
+	assert.Equal(t, "failed", err.Error())
+
+	// an event without an actor has no known association, it is not a non-member
+	e.User = nil
+	assert.Equal(t, "", NewEnvelope(e).Actor)
+	ok, err = nonMembers.Match(ctx, e, failing)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, ok)
+}
+
+type fakeMemberPermission struct {
+	user   *openapi.User
+	status [2]bool
+	err    error
+}
+
+func (f fakeMemberPermission) GetRepoMemberPermission(ctx context.Context, owner, repo, login string) (*openapi.User, [2]bool, error) {
+	return f.user, f.status, f.err
+}
+
+func TestNewPermissionResolver(t *testing.T) {
+	admin := true
+	testCases := []struct {
+		in   fakeMemberPermission
+		want Association
+	}{
+		{fakeMemberPermission{status: [2]bool{false, true}, err: errors.New("404")}, AssociationNone},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("read")}}, AssociationRead},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("write")}}, AssociationWrite},
+		{fakeMemberPermission{user: &openapi.User{Permissions: &openapi.Permission{Admin: &admin}}}, AssociationAdmin},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("guest")}}, AssociationRead},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("Maintainer")}}, AssociationAdmin},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("developer")}}, AssociationWrite},
+		{fakeMemberPermission{user: &openapi.User{Permission: filterString("blocked")}}, AssociationNone},
+		{fakeMemberPermission{}, AssociationNone},
+	}
+	for i, tc := range testCases {
+		got, err := NewPermissionResolver(tc.in).Association(context.Background(), "o", "r", "u")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tc.want, got, i)
+	}
+
+	_, err := NewPermissionResolver(fakeMemberPermission{err: errors.New("failed")}).Association(context.Background(), "o", "r", "u")
+	assert.Equal(t, "failed", err.Error())
+}
+
+func TestDispatcherOnMatch(t *testing.T) {
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+
+	var calls []string
+	err := d.OnMatch(&Filter{Repos: []string{"ibforuorg/*"}, Actions: []string{"open"}}, func(ctx context.Context, e Event) error {
+		calls = append(calls, EventTypeOf(e))
+		return nil
+	})
+	assert.Equal(t, nil, err)
+	err = d.OnMatch(&Filter{Kinds: []EventKind{EventKindIssue}, Associations: []Association{AssociationNone}}, func(ctx context.Context, e Event) error {
+		t.Error("association filter should fail without a resolver")
+		return nil
+	})
+	assert.Equal(t, nil, err)
+
+	err = d.OnMatch(&Filter{Kinds: []EventKind{"merge_request"}}, func(ctx context.Context, e Event) error {
+		return nil
+	})
+	assert.Equal(t, `unknown event kind "merge_request"`, err.Error())
+	assert.Equal(t, 0, len(d.routes[""]))
+
+	pr := readWebHookTestdata(t, webhookTestDataDir+"pr_create.json", nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pullRequestEvent, pr))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{pullRequestEvent}, calls)
+
+	issue := readWebHookTestdata(t, webhookTestDataDir+"issues_create.json", nil)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, issueEvent, issue))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, filterErrorMessage+"\n", w.Body.String())
+
+	d.SetAssociationResolver(AssociationResolverFunc(func(ctx context.Context, org, repo, login string) (Association, error) {
+		return AssociationWrite, nil
+	}))
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, issueEvent, issue))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{pullRequestEvent, issueEvent}, calls)
+
+	push := readWebHookTestdata(t, webhookTestDataDir+"push_code.json", nil)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// repeated kinds register the handler once
+	err = d.OnMatch(&Filter{Kinds: []EventKind{EventKindPush, EventKindPush}}, func(ctx context.Context, e Event) error {
+		calls = append(calls, EventTypeOf(e))
+		return nil
+	})
+	assert.Equal(t, nil, err)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, pushEvent, push))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{pullRequestEvent, issueEvent, pushEvent}, calls)
+}
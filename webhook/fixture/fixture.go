@@ -0,0 +1,276 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package fixture records GitCode webhook deliveries to disk and replays them,
+// re-signed with a test secret, against a handler.
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const fileExt = ".json"
+
+var (
+	errorNilFixture = errors.New("fixture should be non-nil")
+	errorNotJSON    = errors.New("payload is not valid JSON")
+
+	unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+	// redactedHeaders carry secrets or signatures that are replaced on replay.
+	redactedHeaders = []string{webhook.HeaderSignature, webhook.HeaderToken}
+)
+
+// Fixture is a recorded webhook delivery.
+type Fixture struct {
+	EventType  string          `json:"event_type"`
+	Delivery   string          `json:"delivery,omitempty"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Header     http.Header     `json:"header"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// New creates a fixture from a delivery. Signature and token headers are dropped.
+func New(header http.Header, payload []byte) (*Fixture, error) {
+	if !json.Valid(payload) {
+		return nil, errorNotJSON
+	}
+
+	h := header.Clone()
+	for _, name := range redactedHeaders {
+		h.Del(name)
+	}
+	return &Fixture{
+		EventType:  h.Get(webhook.HeaderEvent),
+		Delivery:   h.Get(webhook.HeaderDelivery),
+		RecordedAt: time.Now().UTC(),
+		Header:     h,
+		Payload:    append(json.RawMessage(nil), payload...),
+	}, nil
+}
+
+// FileName returns the name the fixture is saved under, such as push-hook_<delivery>.json.
+func (f *Fixture) FileName() string {
+	id := f.Delivery
+	if id == "" {
+		id = strconv.FormatInt(f.RecordedAt.UnixNano(), 10)
+	}
+	name := unsafeFileChars.ReplaceAllString(f.EventType, "-") + "_" + unsafeFileChars.ReplaceAllString(id, "-")
+	return strings.ToLower(name) + fileExt
+}
+
+// Save writes the fixture into dir and returns the file path.
+func (f *Fixture) Save(dir string) (string, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, f.FileName())
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a fixture saved by Save.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := new(Fixture)
+	if err = json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// LoadDir reads all fixtures in dir, sorted by the time they were recorded.
+func LoadDir(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		f, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool {
+		return fixtures[i].RecordedAt.Before(fixtures[j].RecordedAt)
+	})
+	return fixtures, nil
+}
+
+// Recorder is an http.Handler that saves every delivery into Dir and then passes
+// the request, with its body intact, to Next. A nil Next answers 200.
+type Recorder struct {
+	Dir  string
+	Next http.Handler
+	// OnError is called when a delivery can not be recorded. The request is still passed to Next.
+	OnError func(r *http.Request, err error)
+	// MaxBodySize limits the bodies read, larger ones are answered with 413 and not saved.
+	// 0 selects webhook.DefaultMaxPayloadSize and a negative value disables the limit.
+	MaxBodySize int64
+	// Auth, when set, verifies every delivery before it is saved. Deliveries it rejects
+	// are answered by Auth and neither saved nor passed to Next.
+	Auth *webhook.GitCodeAuthentication
+
+	recorded atomic.Int64
+}
+
+// NewRecorder creates dir if needed and returns a Recorder writing into it.
+func NewRecorder(dir string, next http.Handler) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{Dir: dir, Next: next}, nil
+}
+
+// Recorded returns the number of deliveries saved so far.
+func (rc *Recorder) Recorded() int64 {
+	return rc.recorded.Load()
+}
+
+func (rc *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limit := rc.MaxBodySize
+	if limit == 0 {
+		limit = webhook.DefaultMaxPayloadSize
+	}
+	buf, err := webhook.ReadPayloadLimit(w, r, limit)
+	if err != nil {
+		return
+	}
+
+	if rc.Auth != nil {
+		auth := rc.Auth.Clone()
+		if err, unwritten := auth.Auth(w, r); err != nil {
+			var ae *webhook.AuthError
+			if unwritten && errors.As(err, &ae) {
+				http.Error(w, ae.Message, ae.StatusCode)
+			}
+			return
+		}
+	}
+
+	var payload []byte
+	if buf != nil {
+		payload = buf.Bytes()
+	}
+	if err = rc.record(r.Header, payload); err != nil && rc.OnError != nil {
+		rc.OnError(r, err)
+	}
+
+	if rc.Next == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	rc.Next.ServeHTTP(w, r)
+}
+
+func (rc *Recorder) record(header http.Header, payload []byte) error {
+	f, err := New(header, payload)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Save(rc.Dir); err != nil {
+		return err
+	}
+
+	rc.recorded.Add(1)
+	return nil
+}
+
+// Replayer sends recorded deliveries signed with Secret.
+type Replayer struct {
+	Secret []byte
+	// Client sends the requests of Replay, http.DefaultClient when nil.
+	Client *http.Client
+	// NewDelivery replaces the recorded delivery ID so replay protection does not reject it.
+	NewDelivery bool
+
+	seq atomic.Int64
+}
+
+// NewRequest builds a POST to target carrying the recorded headers and payload, with a fresh
+// signature and timestamp.
+func (rp *Replayer) NewRequest(ctx context.Context, f *Fixture, target string) (*http.Request, error) {
+	if f == nil {
+		return nil, errorNilFixture
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(f.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = f.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set(webhook.HeaderEvent, f.EventType)
+	req.Header.Set("User-Agent", webhook.UserAgent)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(rp.Secret, f.Payload))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if rp.NewDelivery {
+		req.Header.Set(webhook.HeaderDelivery, fmt.Sprintf("replay-%d-%d", time.Now().UnixNano(), rp.seq.Add(1)))
+	}
+	return req, nil
+}
+
+// Replay POSTs the fixture to target.
+func (rp *Replayer) Replay(ctx context.Context, f *Fixture, target string) (*http.Response, error) {
+	req, err := rp.NewRequest(ctx, f, target)
+	if err != nil {
+		return nil, err
+	}
+
+	client := rp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// ServeHandler passes the fixture directly to h and returns the recorded response.
+func (rp *Replayer) ServeHandler(h http.Handler, f *Fixture) (*httptest.ResponseRecorder, error) {
+	req, err := rp.NewRequest(context.Background(), f, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w, nil
+}
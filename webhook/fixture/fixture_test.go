@@ -0,0 +1,168 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const (
+	recordedSecret = "recorded-secret"
+	replaySecret   = "replay-secret"
+)
+
+func readPushPayload(t *testing.T) []byte {
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "webhook", "push_code.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	payload := readPushPayload(t)
+
+	var passed []byte
+	rc, err := NewRecorder(dir, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(r.Body)
+		passed = buf.Bytes()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	assert.Equal(t, nil, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(payload))
+	req.Header.Set(webhook.HeaderEvent, "Push Hook")
+	req.Header.Set(webhook.HeaderDelivery, "88e1681c-bed2")
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign([]byte(recordedSecret), payload))
+	req.Header.Set(webhook.HeaderToken, recordedSecret)
+	req.Header.Set("User-Agent", webhook.UserAgent)
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, payload, passed)
+	assert.Equal(t, int64(1), rc.Recorded())
+
+	fixtures, err := LoadDir(dir)
+	assert.Equal(t, nil, err)
+	if !assert.Equal(t, 1, len(fixtures)) {
+		return
+	}
+	f := fixtures[0]
+	assert.Equal(t, "Push Hook", f.EventType)
+	assert.Equal(t, "88e1681c-bed2", f.Delivery)
+	assert.Equal(t, "", f.Header.Get(webhook.HeaderSignature))
+	assert.Equal(t, "", f.Header.Get(webhook.HeaderToken))
+	assert.Equal(t, "push-hook_88e1681c-bed2.json", f.FileName())
+
+	d, err := webhook.NewDispatcher([]byte(replaySecret))
+	assert.Equal(t, nil, err)
+	var pushed *webhook.PushEvent
+	d.OnPush(func(ctx context.Context, e *webhook.PushEvent) error {
+		pushed = e
+		return nil
+	})
+
+	rp := &Replayer{Secret: []byte(replaySecret)}
+	resp, err := rp.ServeHandler(d, f)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	if assert.NotNil(t, pushed) {
+		assert.Equal(t, "ibforu", *pushed.GetAuthor())
+	}
+
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	rp = &Replayer{Secret: []byte(recordedSecret), NewDelivery: true}
+	res, err := rp.Replay(context.Background(), f, srv.URL)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	_ = res.Body.Close()
+
+	r1, _ := rp.NewRequest(context.Background(), f, srv.URL)
+	r2, _ := rp.NewRequest(context.Background(), f, srv.URL)
+	assert.NotEqual(t, r1.Header.Get(webhook.HeaderDelivery), r2.Header.Get(webhook.HeaderDelivery))
+
+	_, err = rp.NewRequest(context.Background(), nil, srv.URL)
+	assert.Equal(t, errorNilFixture, err)
+}
+
+func TestRecorderSkipsInvalidPayload(t *testing.T) {
+	var recordErr error
+	rc, _ := NewRecorder(t.TempDir(), nil)
+	rc.OnError = func(r *http.Request, err error) {
+		recordErr = err
+	}
+
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader([]byte("not json"))))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, errors.Is(recordErr, errorNotJSON))
+	assert.Equal(t, int64(0), rc.Recorded())
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestRecorderLimitsAndAuth(t *testing.T) {
+	payload := readPushPayload(t)
+	newRequest := func(secret string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(payload))
+		req.Header.Set(webhook.HeaderEvent, "Push Hook")
+		req.Header.Set(webhook.HeaderDelivery, "1")
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign([]byte(secret), payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", webhook.UserAgent)
+		return req
+	}
+
+	passed := 0
+	rc, _ := NewRecorder(t.TempDir(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed++
+	}))
+	rc.MaxBodySize = 16
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, newRequest(recordedSecret))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, int64(0), rc.Recorded())
+	assert.Equal(t, 0, passed)
+
+	rc.MaxBodySize = 0
+	rc.Auth = new(webhook.GitCodeAuthentication)
+	_ = rc.Auth.SetSignKey([]byte(recordedSecret))
+	w = httptest.NewRecorder()
+	rc.ServeHTTP(w, newRequest("wrong"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, int64(0), rc.Recorded())
+	assert.Equal(t, 0, passed)
+
+	w = httptest.NewRecorder()
+	rc.ServeHTTP(w, newRequest(recordedSecret))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(1), rc.Recorded())
+	assert.Equal(t, 1, passed)
+}
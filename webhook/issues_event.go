@@ -41,6 +41,16 @@ type Attributes struct {
 	SourceBranch *string            `json:"source_branch,omitempty"`
 	CreateTime   *openapi.Timestamp `json:"created_at,omitempty"`
 	UpdatedTime  *openapi.Timestamp `json:"updated_at,omitempty"`
+
+	// The fields below are only sent for pull requests.
+	Title          *string         `json:"title,omitempty"`
+	LastCommit     *PushCommit     `json:"last_commit,omitempty"`
+	OldRev         *string         `json:"oldrev,omitempty"`
+	WorkInProgress *bool           `json:"work_in_progress,omitempty"`
+	Draft          *bool           `json:"draft,omitempty"`
+	MergeStatus    *string         `json:"merge_status,omitempty"`
+	Assignees      []*openapi.User `json:"assignee_list,omitempty"`
+	Reviewers      []*openapi.User `json:"reviewer_list,omitempty"`
 }
 
 type IssuePart struct {
@@ -0,0 +1,123 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+type JobEvent struct {
+	UUID          *string            `json:"uuid,omitempty"`
+	ObjectKind    *string            `json:"object_kind,omitempty"`
+	Ref           *string            `json:"ref,omitempty"`
+	Tag           *bool              `json:"tag,omitempty"`
+	BeforeSHA     *string            `json:"before_sha,omitempty"`
+	SHA           *string            `json:"sha,omitempty"`
+	ID            *json.Number       `json:"build_id,omitempty"`
+	Name          *string            `json:"build_name,omitempty"`
+	Stage         *string            `json:"build_stage,omitempty"`
+	Status        *string            `json:"build_status,omitempty"`
+	FailureReason *string            `json:"build_failure_reason,omitempty"`
+	Duration      *float64           `json:"build_duration,omitempty"`
+	PipelineID    *json.Number       `json:"pipeline_id,omitempty"`
+	URL           *string            `json:"build_url,omitempty"`
+	User          *openapi.User      `json:"user,omitempty"`
+	Repository    *Project           `json:"project,omitempty"`
+	CreateTime    *openapi.Timestamp `json:"build_created_at,omitempty"`
+	FinishTime    *openapi.Timestamp `json:"build_finished_at,omitempty"`
+}
+
+func (j *JobEvent) GetAction() *string {
+	return nil
+}
+
+// GetActionDetail returns why the job failed, if it did.
+func (j *JobEvent) GetActionDetail() *string {
+	return j.FailureReason
+}
+
+// GetState returns the job status, such as pending, running, success or failed.
+func (j *JobEvent) GetState() *string {
+	return j.Status
+}
+func (j *JobEvent) GetOrg() *string {
+	if j.Repository == nil {
+		return nil
+	}
+
+	return j.Repository.Namespace
+}
+func (j *JobEvent) GetRepo() *string {
+	if j.Repository == nil {
+		return nil
+	}
+
+	return j.Repository.Name
+}
+func (j *JobEvent) GetHtmlURL() *string {
+	return j.URL
+}
+func (j *JobEvent) GetBase() *string {
+	return j.Ref
+}
+func (j *JobEvent) GetHead() *string {
+	return j.SHA
+}
+func (j *JobEvent) GetNumber() *string {
+	return nil
+}
+func (j *JobEvent) GetID() *string {
+	if j.ID == nil {
+		return nil
+	}
+
+	n := j.ID.String()
+	return &n
+}
+func (j *JobEvent) GetAuthor() *string {
+	if j.User == nil {
+		return nil
+	}
+
+	return j.User.UserName
+}
+func (j *JobEvent) GetCommentID() *string {
+	return nil
+}
+func (j *JobEvent) GetCommentKind() *string {
+	return nil
+}
+func (j *JobEvent) GetComment() *string {
+	return nil
+}
+func (j *JobEvent) GetCommenter() *string {
+	return nil
+}
+func (j *JobEvent) GetCreateTime() *string {
+	if j.CreateTime == nil {
+		return nil
+	}
+
+	return j.CreateTime.ToString()
+}
+
+func (j *JobEvent) GetUpdateTime() *string {
+	if j.FinishTime == nil {
+		return nil
+	}
+
+	return j.FinishTime.ToString()
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+type MemberEvent struct {
+	UUID        *string            `json:"uuid,omitempty"`
+	EventName   *string            `json:"event_name,omitempty"`
+	AccessLevel *string            `json:"access_level,omitempty"`
+	ProjectID   *json.Number       `json:"project_id,omitempty"`
+	ProjectName *string            `json:"project_name,omitempty"`
+	ProjectPath *string            `json:"project_path_with_namespace,omitempty"`
+	UserID      *json.Number       `json:"user_id,omitempty"`
+	UserName    *string            `json:"user_username,omitempty"`
+	UserEmail   *string            `json:"user_email,omitempty"`
+	Repository  *Project           `json:"project,omitempty"`
+	CreateTime  *openapi.Timestamp `json:"created_at,omitempty"`
+	UpdatedTime *openapi.Timestamp `json:"updated_at,omitempty"`
+}
+
+// GetAction returns the event name, such as user_add_to_team or user_remove_from_team.
+func (m *MemberEvent) GetAction() *string {
+	return m.EventName
+}
+func (m *MemberEvent) GetActionDetail() *string {
+	return nil
+}
+
+// GetState returns the access level of the member, such as Developer or Maintainer.
+func (m *MemberEvent) GetState() *string {
+	return m.AccessLevel
+}
+func (m *MemberEvent) GetOrg() *string {
+	if m.Repository != nil && m.Repository.Namespace != nil {
+		return m.Repository.Namespace
+	}
+	if m.ProjectPath == nil {
+		return nil
+	}
+
+	org, _, found := strings.Cut(*m.ProjectPath, "/")
+	if !found {
+		return nil
+	}
+	return &org
+}
+func (m *MemberEvent) GetRepo() *string {
+	if m.Repository != nil && m.Repository.Name != nil {
+		return m.Repository.Name
+	}
+
+	return m.ProjectName
+}
+func (m *MemberEvent) GetHtmlURL() *string {
+	if m.Repository == nil {
+		return nil
+	}
+
+	return m.Repository.HTMLURL
+}
+func (m *MemberEvent) GetBase() *string {
+	return nil
+}
+func (m *MemberEvent) GetHead() *string {
+	return nil
+}
+func (m *MemberEvent) GetNumber() *string {
+	return nil
+}
+func (m *MemberEvent) GetID() *string {
+	if m.UserID == nil {
+		return nil
+	}
+
+	n := m.UserID.String()
+	return &n
+}
+
+// GetAuthor returns the username of the member that was added, changed or removed.
+func (m *MemberEvent) GetAuthor() *string {
+	return m.UserName
+}
+func (m *MemberEvent) GetCommentID() *string {
+	return nil
+}
+func (m *MemberEvent) GetCommentKind() *string {
+	return nil
+}
+func (m *MemberEvent) GetComment() *string {
+	return nil
+}
+func (m *MemberEvent) GetCommenter() *string {
+	return nil
+}
+func (m *MemberEvent) GetCreateTime() *string {
+	if m.CreateTime == nil {
+		return nil
+	}
+
+	return m.CreateTime.ToString()
+}
+
+func (m *MemberEvent) GetUpdateTime() *string {
+	if m.UpdatedTime == nil {
+		return nil
+	}
+
+	return m.UpdatedTime.ToString()
+}
@@ -0,0 +1,288 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package metrics exports webhook counters and handler latencies in the Prometheus text
+// format without depending on a Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const (
+	// ContentType is the content type of the Prometheus text exposition format.
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	defaultNamespace = "gitcode_webhook"
+	// otherEvent replaces event types this package does not model, the header is not
+	// authenticated when a request is received and must not create unbounded series.
+	otherEvent = "other"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the handler duration histogram.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var knownEvents = map[string]bool{
+	webhook.EventTypePush:        true,
+	webhook.EventTypeIssue:       true,
+	webhook.EventTypePullRequest: true,
+	webhook.EventTypeNote:        true,
+	webhook.EventTypeTagPush:     true,
+	webhook.EventTypeRelease:     true,
+	webhook.EventTypePipeline:    true,
+	webhook.EventTypeJob:         true,
+	webhook.EventTypeWikiPage:    true,
+	webhook.EventTypeMember:      true,
+	webhook.EventTypeRepository:  true,
+}
+
+func eventLabel(eventType string) string {
+	if knownEvents[eventType] {
+		return eventType
+	}
+	return otherEvent
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// Exporter is a webhook.Observer counting requests and measuring handler latency.
+// It is an http.Handler serving the metrics in the Prometheus text format:
+//
+//	gitcode_webhook_received_total{event}
+//	gitcode_webhook_rejected_total{reason}
+//	gitcode_webhook_parsed_total{event,result}
+//	gitcode_webhook_handled_total{event,result}
+//	gitcode_webhook_handler_duration_seconds{event}
+type Exporter struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	received  map[string]uint64
+	rejected  map[string]uint64
+	parsed    map[[2]string]uint64
+	handled   map[[2]string]uint64
+	durations map[string]*histogram
+}
+
+var _ webhook.Observer = (*Exporter)(nil)
+
+// NewExporter creates an Exporter. The metric names start with namespace,
+// "gitcode_webhook" when empty, and the histogram uses buckets, DefaultBuckets when nil.
+func NewExporter(namespace string, buckets []float64) *Exporter {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Exporter{
+		namespace: namespace,
+		buckets:   buckets,
+		received:  map[string]uint64{},
+		rejected:  map[string]uint64{},
+		parsed:    map[[2]string]uint64{},
+		handled:   map[[2]string]uint64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+func (e *Exporter) Received(_ context.Context, r *http.Request) {
+	e.mu.Lock()
+	e.received[eventLabel(r.Header.Get(webhook.HeaderEvent))]++
+	e.mu.Unlock()
+}
+
+func (e *Exporter) Rejected(_ context.Context, _ *http.Request, err *webhook.AuthError) {
+	e.mu.Lock()
+	e.rejected[string(err.Code)]++
+	e.mu.Unlock()
+}
+
+func (e *Exporter) Parsed(_ context.Context, eventType, _ string, err error) {
+	result := "success"
+	switch {
+	case errors.Is(err, webhook.ErrUnknownEventType):
+		result = "unknown_event"
+	case err != nil:
+		result = "error"
+	}
+
+	e.mu.Lock()
+	e.parsed[[2]string{eventLabel(eventType), result}]++
+	e.mu.Unlock()
+}
+
+func (e *Exporter) Handled(_ context.Context, d *webhook.Delivery, elapsed time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	event := eventLabel(d.EventType)
+	seconds := elapsed.Seconds()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.handled[[2]string{event, result}]++
+	h := e.durations[event]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(e.buckets))}
+		e.durations[event] = h
+	}
+	for i, le := range e.buckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = e.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	e.mu.Lock()
+	e.writeCounter(cw, "received_total", "Webhook requests received.", []string{"event"}, single(e.received))
+	e.writeCounter(cw, "rejected_total", "Webhook requests rejected by authentication, by reason.", []string{"reason"}, single(e.rejected))
+	e.writeCounter(cw, "parsed_total", "Webhook payloads decoded, by result.", []string{"event", "result"}, pairs(e.parsed))
+	e.writeCounter(cw, "handled_total", "Webhook events passed to handlers, by result.", []string{"event", "result"}, pairs(e.handled))
+	e.writeHistogram(cw)
+	e.mu.Unlock()
+
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+type series struct {
+	labels []string
+	value  uint64
+}
+
+func single(m map[string]uint64) []series {
+	out := make([]series, 0, len(m))
+	for k, v := range m {
+		out = append(out, series{labels: []string{k}, value: v})
+	}
+	return out
+}
+
+func pairs(m map[[2]string]uint64) []series {
+	out := make([]series, 0, len(m))
+	for k, v := range m {
+		out = append(out, series{labels: []string{k[0], k[1]}, value: v})
+	}
+	return out
+}
+
+func (e *Exporter) writeCounter(w io.Writer, name, help string, labelNames []string, ss []series) {
+	name = e.namespace + "_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	sort.Slice(ss, func(i, j int) bool {
+		return strings.Join(ss[i].labels, "\xff") < strings.Join(ss[j].labels, "\xff")
+	})
+	for _, s := range ss {
+		fmt.Fprintf(w, "%s%s %d\n", name, formatLabels(labelNames, s.labels), s.value)
+	}
+}
+
+func (e *Exporter) writeHistogram(w io.Writer) {
+	name := e.namespace + "_handler_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time spent in webhook handlers.\n# TYPE %s histogram\n", name, name)
+
+	events := make([]string, 0, len(e.durations))
+	for event := range e.durations {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	for _, event := range events {
+		h := e.durations[event]
+		var cumulative uint64
+		for i, le := range e.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name,
+				formatLabels([]string{"event", "le"}, []string{event, formatFloat(le)}), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels([]string{"event", "le"}, []string{event, "+Inf"}), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels([]string{"event"}, []string{event}), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels([]string{"event"}, []string{event}), h.count)
+	}
+}
+
+func formatLabels(names, values []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(names[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
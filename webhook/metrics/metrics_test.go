@@ -0,0 +1,127 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/webhooktest"
+)
+
+const secret = "1234"
+
+func TestExporterWithDispatcher(t *testing.T) {
+	exp := NewExporter("", nil)
+	d, err := webhook.NewDispatcher([]byte(secret))
+	assert.Equal(t, nil, err)
+	d.SetObserver(exp)
+
+	d.OnIssue(func(ctx context.Context, e *webhook.IssueEvent) error {
+		return nil
+	})
+	d.OnPush(func(ctx context.Context, e *webhook.PushEvent) error {
+		return errors.New("failed")
+	})
+
+	issue := webhooktest.IssueEvent("org", "repo", 1, "open", "alice")
+	webhooktest.Serve(d, webhooktest.NewEventRequest(issue, []byte(secret)))
+	webhooktest.Serve(d, webhooktest.NewEventRequest(issue, []byte(secret)))
+	push := webhooktest.PushEvent("org", "repo", "refs/heads/main", "a", "b", "alice")
+	webhooktest.Serve(d, webhooktest.NewEventRequest(push, []byte(secret)))
+	webhooktest.Serve(d, webhooktest.NewEventRequest(push, []byte("wrong")))
+	webhooktest.Serve(d, webhooktest.NewSignedRequest("Made Up \"Hook\"", []byte("{}"), []byte(secret)))
+
+	w := httptest.NewRecorder()
+	exp.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	out := w.Body.String()
+	for _, line := range []string{
+		"# TYPE gitcode_webhook_received_total counter",
+		`gitcode_webhook_received_total{event="Issue Hook"} 2`,
+		`gitcode_webhook_received_total{event="Push Hook"} 2`,
+		`gitcode_webhook_received_total{event="other"} 1`,
+		`gitcode_webhook_rejected_total{reason="invalid_signature"} 1`,
+		`gitcode_webhook_parsed_total{event="Issue Hook",result="success"} 2`,
+		`gitcode_webhook_parsed_total{event="other",result="unknown_event"} 1`,
+		`gitcode_webhook_handled_total{event="Issue Hook",result="success"} 2`,
+		`gitcode_webhook_handled_total{event="Push Hook",result="error"} 1`,
+		"# TYPE gitcode_webhook_handler_duration_seconds histogram",
+		`gitcode_webhook_handler_duration_seconds_bucket{event="Issue Hook",le="+Inf"} 2`,
+		`gitcode_webhook_handler_duration_seconds_count{event="Push Hook"} 1`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestExporterHandlerPanic(t *testing.T) {
+	exp := NewExporter("", nil)
+	d, err := webhook.NewDispatcher([]byte(secret))
+	assert.Equal(t, nil, err)
+	d.SetObserver(exp)
+	d.OnIssue(func(ctx context.Context, e *webhook.IssueEvent) error {
+		panic("boom")
+	})
+
+	issue := webhooktest.IssueEvent("org", "repo", 1, "open", "alice")
+	assert.PanicsWithValue(t, "boom", func() {
+		webhooktest.Serve(d, webhooktest.NewEventRequest(issue, []byte(secret)))
+	})
+
+	// the worker pool recovers from the panic
+	pool := webhook.NewWorkerPool(d.Handle, webhook.WorkerPoolOptions{Workers: 1, MaxAttempts: 1})
+	d.SetQueue(pool)
+	pool.Start()
+	webhooktest.Serve(d, webhooktest.NewEventRequest(issue, []byte(secret)))
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+
+	var b strings.Builder
+	_, _ = exp.WriteTo(&b)
+	assert.Contains(t, b.String(), `gitcode_webhook_handled_total{event="Issue Hook",result="error"} 2`+"\n")
+	assert.NotContains(t, b.String(), `gitcode_webhook_handled_total{event="Issue Hook",result="success"}`)
+}
+
+func TestExporterHistogram(t *testing.T) {
+	exp := NewExporter("bot", []float64{1, 0.1})
+	ctx := context.Background()
+	for _, d := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second} {
+		exp.Handled(ctx, &webhook.Delivery{EventType: webhook.EventTypeNote}, d, nil)
+	}
+
+	var b strings.Builder
+	n, err := exp.WriteTo(&b)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(b.Len()), n)
+
+	event := fmt.Sprintf("event=%q", webhook.EventTypeNote)
+	assert.Contains(t, b.String(), "bot_handler_duration_seconds_bucket{"+event+`,le="0.1"} 1`+"\n")
+	assert.Contains(t, b.String(), "bot_handler_duration_seconds_bucket{"+event+`,le="1"} 2`+"\n")
+	assert.Contains(t, b.String(), "bot_handler_duration_seconds_bucket{"+event+`,le="+Inf"} 3`+"\n")
+	assert.Contains(t, b.String(), "bot_handler_duration_seconds_sum{"+event+"} 2.55\n")
+	assert.Contains(t, b.String(), "bot_handler_duration_seconds_count{"+event+"} 3\n")
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, `{a="x\"y\\z\n"}`, formatLabels([]string{"a"}, []string{"x\"y\\z\n"}))
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Observer is notified of the steps a webhook request goes through, for logging and metrics.
+// Methods are called synchronously and must be safe for concurrent use.
+type Observer interface {
+	// Received is called by GitCodeAuthentication.Auth before any check.
+	Received(ctx context.Context, r *http.Request)
+	// Rejected is called when Auth fails, err.Code tells why.
+	Rejected(ctx context.Context, r *http.Request, err *AuthError)
+	// Parsed is called after decoding the payload of an authenticated request. err wraps
+	// ErrUnknownEventType or ErrPayloadParse on failure.
+	Parsed(ctx context.Context, eventType, delivery string, err error)
+	// Handled is called by the Dispatcher after the handlers of an event ran. err wraps
+	// ErrHandlerPanic when a handler panicked.
+	Handled(ctx context.Context, d *Delivery, elapsed time.Duration, err error)
+}
+
+// NopObserver ignores all notifications. Embed it to implement only some methods of Observer.
+type NopObserver struct{}
+
+func (NopObserver) Received(context.Context, *http.Request)                  {}
+func (NopObserver) Rejected(context.Context, *http.Request, *AuthError)      {}
+func (NopObserver) Parsed(context.Context, string, string, error)            {}
+func (NopObserver) Handled(context.Context, *Delivery, time.Duration, error) {}
+
+type multiObserver []Observer
+
+// MultiObserver returns an Observer notifying each of observers in order.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+func (m multiObserver) Received(ctx context.Context, r *http.Request) {
+	for _, o := range m {
+		o.Received(ctx, r)
+	}
+}
+
+func (m multiObserver) Rejected(ctx context.Context, r *http.Request, err *AuthError) {
+	for _, o := range m {
+		o.Rejected(ctx, r, err)
+	}
+}
+
+func (m multiObserver) Parsed(ctx context.Context, eventType, delivery string, err error) {
+	for _, o := range m {
+		o.Parsed(ctx, eventType, delivery, err)
+	}
+}
+
+func (m multiObserver) Handled(ctx context.Context, d *Delivery, elapsed time.Duration, err error) {
+	for _, o := range m {
+		o.Handled(ctx, d, elapsed, err)
+	}
+}
+
+// SlogObserver logs notifications with log/slog: received requests and parsed events at
+// debug level, rejections and parse failures as warnings, handler results as info or error.
+type SlogObserver struct {
+	Logger *slog.Logger
+}
+
+// NewSlogObserver returns an Observer logging to l, or to slog.Default when l is nil.
+func NewSlogObserver(l *slog.Logger) *SlogObserver {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogObserver{Logger: l}
+}
+
+func (o *SlogObserver) Received(ctx context.Context, r *http.Request) {
+	o.Logger.DebugContext(ctx, "webhook received",
+		slog.String("event", r.Header.Get(headerEventType)),
+		slog.String("delivery", r.Header.Get(headerEventGUID)),
+		slog.String("remote", r.RemoteAddr))
+}
+
+func (o *SlogObserver) Rejected(ctx context.Context, r *http.Request, err *AuthError) {
+	o.Logger.WarnContext(ctx, "webhook rejected",
+		slog.String("event", r.Header.Get(headerEventType)),
+		slog.String("delivery", r.Header.Get(headerEventGUID)),
+		slog.String("remote", r.RemoteAddr),
+		slog.String("reason", string(err.Code)),
+		slog.Int("status", err.StatusCode),
+		slog.String("error", err.Error()))
+}
+
+func (o *SlogObserver) Parsed(ctx context.Context, eventType, delivery string, err error) {
+	if err != nil {
+		o.Logger.WarnContext(ctx, "webhook payload not parsed",
+			slog.String("event", eventType),
+			slog.String("delivery", delivery),
+			slog.String("error", err.Error()))
+		return
+	}
+	o.Logger.DebugContext(ctx, "webhook parsed",
+		slog.String("event", eventType),
+		slog.String("delivery", delivery))
+}
+
+func (o *SlogObserver) Handled(ctx context.Context, d *Delivery, elapsed time.Duration, err error) {
+	attrs := []any{
+		slog.String("event", d.EventType),
+		slog.String("delivery", d.GUID),
+		slog.Duration("elapsed", elapsed),
+	}
+	if d.Attempt > 0 {
+		attrs = append(attrs, slog.Int("attempt", d.Attempt))
+	}
+	if err != nil {
+		o.Logger.ErrorContext(ctx, "webhook handler failed", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	o.Logger.InfoContext(ctx, "webhook handled", attrs...)
+}
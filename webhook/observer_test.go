@@ -0,0 +1,137 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (o *recordingObserver) add(call string) {
+	o.mu.Lock()
+	o.calls = append(o.calls, call)
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) Received(_ context.Context, r *http.Request) {
+	o.add("received " + r.Header.Get(headerEventType))
+}
+
+func (o *recordingObserver) Rejected(_ context.Context, _ *http.Request, err *AuthError) {
+	o.add("rejected " + string(err.Code))
+}
+
+func (o *recordingObserver) Parsed(_ context.Context, eventType, _ string, err error) {
+	if err != nil {
+		o.add("parse failed " + eventType)
+		return
+	}
+	o.add("parsed " + eventType)
+}
+
+func (o *recordingObserver) Handled(_ context.Context, d *Delivery, elapsed time.Duration, err error) {
+	if err != nil {
+		o.add("handle failed " + d.EventType)
+		return
+	}
+	o.add("handled " + d.EventType)
+}
+
+func TestDispatcherObserver(t *testing.T) {
+	o := new(recordingObserver)
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+	d.SetObserver(o)
+	d.OnNote(func(ctx context.Context, e *NoteEvent) error {
+		return errors.New("failed")
+	})
+
+	note := readWebHookTestdata(t, webhookTestDataDir+"pr_note.json", nil)
+	d.ServeHTTP(httptest.NewRecorder(), newDispatcherRequest(t, noteEvent, note))
+
+	req := newDispatcherRequest(t, noteEvent, note)
+	req.Header.Set(headerEventToken, Sign([]byte("wrong"), note))
+	d.ServeHTTP(httptest.NewRecorder(), req)
+
+	d.ServeHTTP(httptest.NewRecorder(), newDispatcherRequest(t, "Unknown Hook", []byte("{}")))
+
+	assert.Equal(t, []string{
+		"received " + noteEvent, "parsed " + noteEvent, "handle failed " + noteEvent,
+		"received " + noteEvent, "rejected " + string(AuthErrorInvalidSignature),
+		"received Unknown Hook", "parse failed Unknown Hook",
+	}, o.calls)
+
+	o.calls = nil
+	_ = d.Handle(context.Background(), &Job{Delivery: Delivery{EventType: noteEvent, GUID: "1", Payload: note, Attempt: 1}})
+	assert.Equal(t, []string{"parsed " + noteEvent, "handle failed " + noteEvent}, o.calls)
+}
+
+func TestAccessorObserver(t *testing.T) {
+	o := new(recordingObserver)
+	a := new(GitCodeAccessor)
+	a.SetObserver(MultiObserver(o, NopObserver{}))
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/observe", strings.NewReader("{"))
+	req.Header.Set(headerEventType, pushEvent)
+	_, _, _, _, err := a.Parse(httptest.NewRecorder(), req)
+	assert.True(t, errors.Is(err, ErrPayloadParse))
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/observe", strings.NewReader("{}"))
+	req.Header.Set(headerEventType, pushEvent)
+	_, _, _, _ = a.GetAccessor(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"parse failed " + pushEvent, "parsed " + pushEvent}, o.calls)
+}
+
+func TestSlogObserver(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewSlogObserver(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	ctx := context.Background()
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/observe", nil)
+	req.Header.Set(headerEventType, pushEvent)
+	req.Header.Set(headerEventGUID, "d-1")
+	o.Received(ctx, req)
+	o.Rejected(ctx, req, &AuthError{Code: AuthErrorClockSkew, StatusCode: http.StatusUnauthorized, Message: clockSkewErrorMessage})
+	o.Parsed(ctx, pushEvent, "d-1", ErrPayloadParse)
+	o.Parsed(ctx, pushEvent, "d-1", nil)
+	o.Handled(ctx, &Delivery{EventType: pushEvent, GUID: "d-1", Attempt: 2}, time.Second, nil)
+	o.Handled(ctx, &Delivery{EventType: pushEvent, GUID: "d-1"}, time.Second, errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 6, len(lines))
+	assert.Contains(t, lines[0], `level=DEBUG msg="webhook received" event="Push Hook" delivery=d-1`)
+	assert.Contains(t, lines[1], `level=WARN msg="webhook rejected"`)
+	assert.Contains(t, lines[1], `reason=clock_skew status=401`)
+	assert.Contains(t, lines[2], `level=WARN msg="webhook payload not parsed"`)
+	assert.Contains(t, lines[3], `level=DEBUG msg="webhook parsed"`)
+	assert.Contains(t, lines[4], `level=INFO msg="webhook handled" event="Push Hook" delivery=d-1 elapsed=1s attempt=2`)
+	assert.Contains(t, lines[5], `level=ERROR msg="webhook handler failed"`)
+	assert.Contains(t, lines[5], `error=boom`)
+
+	assert.Equal(t, slog.Default(), NewSlogObserver(nil).Logger)
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+type PipelineAttributes struct {
+	ID         *json.Number       `json:"id,omitempty"`
+	Ref        *string            `json:"ref,omitempty"`
+	Tag        *bool              `json:"tag,omitempty"`
+	SHA        *string            `json:"sha,omitempty"`
+	BeforeSHA  *string            `json:"before_sha,omitempty"`
+	Source     *string            `json:"source,omitempty"`
+	Status     *string            `json:"status,omitempty"`
+	Stages     []string           `json:"stages,omitempty"`
+	Duration   *int               `json:"duration,omitempty"`
+	URL        *string            `json:"url,omitempty"`
+	CreateTime *openapi.Timestamp `json:"created_at,omitempty"`
+	FinishTime *openapi.Timestamp `json:"finished_at,omitempty"`
+}
+
+type PipelineMergeRequest struct {
+	ID           *json.Number `json:"id,omitempty"`
+	Number       *int         `json:"iid,omitempty"`
+	Title        *string      `json:"title,omitempty"`
+	SourceBranch *string      `json:"source_branch,omitempty"`
+	TargetBranch *string      `json:"target_branch,omitempty"`
+	State        *string      `json:"state,omitempty"`
+	URL          *string      `json:"url,omitempty"`
+}
+
+type PipelineEvent struct {
+	UUID         *string               `json:"uuid,omitempty"`
+	ObjectKind   *string               `json:"object_kind,omitempty"`
+	Attributes   *PipelineAttributes   `json:"object_attributes,omitempty"`
+	MergeRequest *PipelineMergeRequest `json:"merge_request,omitempty"`
+	User         *openapi.User         `json:"user,omitempty"`
+	Repository   *Project              `json:"project,omitempty"`
+	Commit       *PushCommit           `json:"commit,omitempty"`
+}
+
+func (pl *PipelineEvent) GetAction() *string {
+	return nil
+}
+
+// GetActionDetail returns what triggered the pipeline, such as push or merge_request_event.
+func (pl *PipelineEvent) GetActionDetail() *string {
+	if pl.Attributes == nil {
+		return nil
+	}
+
+	return pl.Attributes.Source
+}
+
+// GetState returns the pipeline status, such as pending, running, success or failed.
+func (pl *PipelineEvent) GetState() *string {
+	if pl.Attributes == nil {
+		return nil
+	}
+
+	return pl.Attributes.Status
+}
+func (pl *PipelineEvent) GetOrg() *string {
+	if pl.Repository == nil {
+		return nil
+	}
+
+	return pl.Repository.Namespace
+}
+func (pl *PipelineEvent) GetRepo() *string {
+	if pl.Repository == nil {
+		return nil
+	}
+
+	return pl.Repository.Name
+}
+func (pl *PipelineEvent) GetHtmlURL() *string {
+	if pl.Attributes == nil {
+		return nil
+	}
+
+	return pl.Attributes.URL
+}
+func (pl *PipelineEvent) GetBase() *string {
+	if pl.Attributes == nil {
+		return nil
+	}
+
+	return pl.Attributes.Ref
+}
+func (pl *PipelineEvent) GetHead() *string {
+	if pl.Attributes == nil {
+		return nil
+	}
+
+	return pl.Attributes.SHA
+}
+
+// GetNumber returns the number of the pull request the pipeline runs for, if any.
+func (pl *PipelineEvent) GetNumber() *string {
+	if pl.MergeRequest == nil || pl.MergeRequest.Number == nil {
+		return nil
+	}
+
+	n := strconv.Itoa(*pl.MergeRequest.Number)
+	return &n
+}
+func (pl *PipelineEvent) GetID() *string {
+	if pl.Attributes == nil || pl.Attributes.ID == nil {
+		return nil
+	}
+
+	n := pl.Attributes.ID.String()
+	return &n
+}
+func (pl *PipelineEvent) GetAuthor() *string {
+	if pl.User == nil {
+		return nil
+	}
+
+	return pl.User.UserName
+}
+func (pl *PipelineEvent) GetCommentID() *string {
+	return nil
+}
+func (pl *PipelineEvent) GetCommentKind() *string {
+	return nil
+}
+func (pl *PipelineEvent) GetComment() *string {
+	return nil
+}
+func (pl *PipelineEvent) GetCommenter() *string {
+	return nil
+}
+func (pl *PipelineEvent) GetCreateTime() *string {
+	if pl.Attributes == nil || pl.Attributes.CreateTime == nil {
+		return nil
+	}
+
+	return pl.Attributes.CreateTime.ToString()
+}
+
+func (pl *PipelineEvent) GetUpdateTime() *string {
+	if pl.Attributes == nil || pl.Attributes.FinishTime == nil {
+		return nil
+	}
+
+	return pl.Attributes.FinishTime.ToString()
+}
@@ -19,6 +19,12 @@ import (
 	"strconv"
 )
 
+const (
+	prActionUpdate = "update"
+
+	changedLabels = "labels"
+)
+
 type PRPart struct {
 	Action       *string       `json:"action,omitempty"`
 	State        *string       `json:"state,omitempty"`
@@ -28,6 +34,140 @@ type PRPart struct {
 	Source       *Project      `json:"source,omitempty"`
 	SourceBranch *string       `json:"source_branch,omitempty"`
 	ID           *json.Number  `json:"id,omitempty"`
+
+	Title          *string         `json:"title,omitempty"`
+	Description    *string         `json:"description,omitempty"`
+	LastCommit     *PushCommit     `json:"last_commit,omitempty"`
+	WorkInProgress *bool           `json:"work_in_progress,omitempty"`
+	Draft          *bool           `json:"draft,omitempty"`
+	MergeStatus    *string         `json:"merge_status,omitempty"`
+	Assignees      []*openapi.User `json:"assignee_list,omitempty"`
+	Reviewers      []*openapi.User `json:"reviewer_list,omitempty"`
+}
+
+// IsDraft reports whether the pull request is a draft or marked as work in progress.
+// Like the other PRPart helpers it is safe to call on nil, which note events about
+// issues carry.
+func (p *PRPart) IsDraft() bool {
+	if p == nil {
+		return false
+	}
+
+	return (p.Draft != nil && *p.Draft) || (p.WorkInProgress != nil && *p.WorkInProgress)
+}
+
+// GetHeadSHA returns the SHA of the last commit of the source branch.
+func (p *PRPart) GetHeadSHA() *string {
+	if p == nil || p.LastCommit == nil {
+		return nil
+	}
+
+	return p.LastCommit.ID
+}
+
+// GetTitle returns the title of the pull request.
+func (p *PRPart) GetTitle() *string {
+	if p == nil {
+		return nil
+	}
+
+	return p.Title
+}
+
+// GetDescription returns the description of the pull request.
+func (p *PRPart) GetDescription() *string {
+	if p == nil {
+		return nil
+	}
+
+	return p.Description
+}
+
+// GetReviewers returns the reviewers of the pull request.
+func (p *PRPart) GetReviewers() []*openapi.User {
+	if p == nil {
+		return nil
+	}
+
+	return p.Reviewers
+}
+
+// GetAssignees returns the assignees of the pull request.
+func (p *PRPart) GetAssignees() []*openapi.User {
+	if p == nil {
+		return nil
+	}
+
+	return p.Assignees
+}
+
+// Change holds the previous and current value of a field updated by the event.
+// Values are kept raw because their type depends on the field.
+type Change struct {
+	Previous json.RawMessage `json:"previous,omitempty"`
+	Current  json.RawMessage `json:"current,omitempty"`
+}
+
+// Changes is the changes block of an event, keyed by field name such as title or labels.
+type Changes map[string]*Change
+
+// Has reports whether field was changed.
+func (c Changes) Has(field string) bool {
+	_, ok := c[field]
+	return ok
+}
+
+// Labels decodes the labels change. ok is false when the labels were not changed.
+func (c Changes) Labels() (previous, current []*openapi.Label, ok bool, err error) {
+	change, ok := c[changedLabels]
+	if !ok || change == nil {
+		return nil, nil, false, nil
+	}
+
+	if previous, err = decodeLabels(change.Previous); err != nil {
+		return nil, nil, true, err
+	}
+	if current, err = decodeLabels(change.Current); err != nil {
+		return nil, nil, true, err
+	}
+	return previous, current, true, nil
+}
+
+func decodeLabels(data json.RawMessage) ([]*openapi.Label, error) {
+	var labels []*openapi.Label
+	if len(data) == 0 {
+		return labels, nil
+	}
+	err := json.Unmarshal(data, &labels)
+	return labels, err
+}
+
+func labelKey(l *openapi.Label) string {
+	if l.Name != "" {
+		return l.Name
+	}
+	return l.Title
+}
+
+// labelsDiff returns the labels in from that are missing in other.
+func labelsDiff(from, other []*openapi.Label) []*openapi.Label {
+	seen := make(map[string]struct{}, len(other))
+	for _, l := range other {
+		if l != nil {
+			seen[labelKey(l)] = struct{}{}
+		}
+	}
+
+	var diff []*openapi.Label
+	for _, l := range from {
+		if l == nil {
+			continue
+		}
+		if _, ok := seen[labelKey(l)]; !ok {
+			diff = append(diff, l)
+		}
+	}
+	return diff
 }
 
 type PullRequestEvent struct {
@@ -40,6 +180,92 @@ type PullRequestEvent struct {
 	Repository  *Project         `json:"project,omitempty"`
 	Labels      []*openapi.Label `json:"labels,omitempty"`
 	PR          *PRPart          `json:"merge_request,omitempty"`
+	Changes     Changes          `json:"changes,omitempty"`
+}
+
+// LabelsAdded returns the labels added by the event, or nil when the labels were not changed.
+func (pr *PullRequestEvent) LabelsAdded() []*openapi.Label {
+	previous, current, ok, err := pr.Changes.Labels()
+	if !ok || err != nil {
+		return nil
+	}
+
+	return labelsDiff(current, previous)
+}
+
+// LabelsRemoved returns the labels removed by the event, or nil when the labels were not changed.
+func (pr *PullRequestEvent) LabelsRemoved() []*openapi.Label {
+	previous, current, ok, err := pr.Changes.Labels()
+	if !ok || err != nil {
+		return nil
+	}
+
+	return labelsDiff(previous, current)
+}
+
+// IsNewCommitPush reports whether the event was sent because commits were pushed to the source branch.
+// GitCode fills oldrev with the previous head SHA only in that case.
+func (pr *PullRequestEvent) IsNewCommitPush() bool {
+	if pr.Attributes == nil || pr.Attributes.Action == nil || *pr.Attributes.Action != prActionUpdate {
+		return false
+	}
+
+	return pr.Attributes.OldRev != nil && *pr.Attributes.OldRev != ""
+}
+
+// IsDraft reports whether the pull request is a draft or marked as work in progress.
+// It falls back to the merge_request block when object_attributes does not say.
+func (pr *PullRequestEvent) IsDraft() bool {
+	if a := pr.Attributes; a != nil && (a.Draft != nil || a.WorkInProgress != nil) {
+		return (a.Draft != nil && *a.Draft) || (a.WorkInProgress != nil && *a.WorkInProgress)
+	}
+
+	return pr.PR.IsDraft()
+}
+
+// GetHeadSHA returns the SHA of the last commit of the source branch.
+func (pr *PullRequestEvent) GetHeadSHA() *string {
+	if pr.Attributes != nil && pr.Attributes.LastCommit != nil {
+		return pr.Attributes.LastCommit.ID
+	}
+
+	return pr.PR.GetHeadSHA()
+}
+
+// GetTitle returns the title of the pull request.
+func (pr *PullRequestEvent) GetTitle() *string {
+	if pr.Attributes != nil && pr.Attributes.Title != nil {
+		return pr.Attributes.Title
+	}
+
+	return pr.PR.GetTitle()
+}
+
+// GetDescription returns the description of the pull request.
+func (pr *PullRequestEvent) GetDescription() *string {
+	if pr.Attributes != nil && pr.Attributes.Comment != nil {
+		return pr.Attributes.Comment
+	}
+
+	return pr.PR.GetDescription()
+}
+
+// GetReviewers returns the reviewers of the pull request.
+func (pr *PullRequestEvent) GetReviewers() []*openapi.User {
+	if pr.Attributes != nil && pr.Attributes.Reviewers != nil {
+		return pr.Attributes.Reviewers
+	}
+
+	return pr.PR.GetReviewers()
+}
+
+// GetAssignees returns the assignees of the pull request.
+func (pr *PullRequestEvent) GetAssignees() []*openapi.User {
+	if pr.Attributes != nil && pr.Attributes.Assignees != nil {
+		return pr.Attributes.Assignees
+	}
+
+	return pr.PR.GetAssignees()
 }
 
 func (pr *PullRequestEvent) GetAction() *string {
@@ -13,25 +13,98 @@
 // limitations under the License.
 package webhook
 
-import "github.com/opensourceways/go-gitcode/openapi"
+import (
+	"encoding/json"
+	"strings"
 
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+const branchRefPrefix = "refs/heads/"
+
+type CommitAuthor struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+}
+
+type PushCommit struct {
+	ID        *string            `json:"id,omitempty"`
+	Message   *string            `json:"message,omitempty"`
+	Title     *string            `json:"title,omitempty"`
+	Timestamp *openapi.Timestamp `json:"timestamp,omitempty"`
+	URL       *string            `json:"url,omitempty"`
+	Author    *CommitAuthor      `json:"author,omitempty"`
+	Added     []string           `json:"added,omitempty"`
+	Modified  []string           `json:"modified,omitempty"`
+	Removed   []string           `json:"removed,omitempty"`
+}
+
+type PushRepository struct {
+	Name        *string `json:"name,omitempty"`
+	URL         *string `json:"url,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Homepage    *string `json:"homepage,omitempty"`
+	GitHTTPURL  *string `json:"git_http_url,omitempty"`
+	GitSSHURL   *string `json:"git_ssh_url,omitempty"`
+}
+
+// PushEvent is a "Push Hook" delivery. GitCode does not report whether a push was forced,
+// telling that apart requires checking in the repository that Before is an ancestor of After.
 type PushEvent struct {
-	UUID         *string            `json:"uuid,omitempty"`
-	EventType    *string            `json:"event_name,omitempty"`
-	ObjectKind   *string            `json:"object_kind,omitempty"`
-	ManualBuild  *bool              `json:"manual_build,omitempty"`
-	Repository   *Project           `json:"project,omitempty"`
-	SourceBranch *string            `json:"git_branch,omitempty"`
-	Author       *string            `json:"user_username,omitempty"`
-	CreateTime   *openapi.Timestamp `json:"created_at,omitempty"`
-	UpdatedTime  *openapi.Timestamp `json:"updated_at,omitempty"`
+	UUID              *string            `json:"uuid,omitempty"`
+	EventType         *string            `json:"event_name,omitempty"`
+	ObjectKind        *string            `json:"object_kind,omitempty"`
+	Before            *string            `json:"before,omitempty"`
+	After             *string            `json:"after,omitempty"`
+	Ref               *string            `json:"ref,omitempty"`
+	BaseRef           *string            `json:"base_ref,omitempty"`
+	CheckoutSHA       *string            `json:"checkout_sha,omitempty"`
+	Message           *string            `json:"message,omitempty"`
+	UserID            *json.Number       `json:"user_id,omitempty"`
+	UserName          *string            `json:"user_name,omitempty"`
+	UserEmail         *string            `json:"user_email,omitempty"`
+	UserAvatar        *string            `json:"user_avatar,omitempty"`
+	ProjectID         *json.Number       `json:"project_id,omitempty"`
+	Commits           []*PushCommit      `json:"commits,omitempty"`
+	TotalCommitsCount *int               `json:"total_commits_count,omitempty"`
+	PushOptions       []string           `json:"push_options,omitempty"`
+	PushRepository    *PushRepository    `json:"repository,omitempty"`
+	CommitNo          *string            `json:"git_commit_no,omitempty"`
+	ManualBuild       *bool              `json:"manual_build,omitempty"`
+	Repository        *Project           `json:"project,omitempty"`
+	SourceBranch      *string            `json:"git_branch,omitempty"`
+	Author            *string            `json:"user_username,omitempty"`
+	CreateTime        *openapi.Timestamp `json:"created_at,omitempty"`
+	UpdatedTime       *openapi.Timestamp `json:"updated_at,omitempty"`
+}
+
+// IsTag reports whether the pushed ref is a tag.
+func (p *PushEvent) IsTag() bool {
+	return p.Ref != nil && strings.HasPrefix(*p.Ref, tagRefPrefix)
+}
+
+// IsBranchCreate reports whether the push created a new branch.
+func (p *PushEvent) IsBranchCreate() bool {
+	return !p.IsTag() && p.Before != nil && *p.Before == zeroSHA
+}
+
+// IsBranchDelete reports whether the push deleted a branch.
+func (p *PushEvent) IsBranchDelete() bool {
+	return !p.IsTag() && p.After != nil && *p.After == zeroSHA
 }
 
+// GetAction returns create, delete or update depending on how the ref changed.
 func (p *PushEvent) GetAction() *string {
-	return nil
+	return refAction(p.Before, p.After)
 }
+
+// GetActionDetail returns the message of the pushed annotated tag, if any.
 func (p *PushEvent) GetActionDetail() *string {
-	return nil
+	if p.Message == nil || *p.Message == "" {
+		return nil
+	}
+
+	return p.Message
 }
 func (p *PushEvent) GetState() *string {
 	return nil
@@ -57,17 +130,29 @@ func (p *PushEvent) GetHtmlURL() *string {
 
 	return p.Repository.HTMLURL
 }
+
+// GetBase returns the pushed branch or tag name.
 func (p *PushEvent) GetBase() *string {
-	return p.SourceBranch
+	if p.SourceBranch != nil {
+		return p.SourceBranch
+	}
+	if p.Ref == nil {
+		return nil
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(*p.Ref, branchRefPrefix), tagRefPrefix)
+	return &name
 }
+
+// GetHead returns the SHA the ref points to after the push.
 func (p *PushEvent) GetHead() *string {
-	return nil
+	return p.After
 }
 func (p *PushEvent) GetNumber() *string {
 	return nil
 }
 func (p *PushEvent) GetID() *string {
-	return nil
+	return p.CheckoutSHA
 }
 func (p *PushEvent) GetAuthor() *string {
 	return p.Author
@@ -85,9 +170,17 @@ func (p *PushEvent) GetCommenter() *string {
 	return nil
 }
 func (p *PushEvent) GetCreateTime() *string {
-	return nil
+	if p.CreateTime == nil {
+		return nil
+	}
+
+	return p.CreateTime.ToString()
 }
 
 func (p *PushEvent) GetUpdateTime() *string {
-	return nil
+	if p.UpdatedTime == nil {
+		return nil
+	}
+
+	return p.UpdatedTime.ToString()
 }
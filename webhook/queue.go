@@ -0,0 +1,303 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultWorkers        = 4
+	defaultQueueSize      = 256
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = time.Minute
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the queue has no room for the job.
+	// The Dispatcher answers it with 503 so GitCode retries the delivery later.
+	ErrQueueFull = errors.New("webhook queue is full")
+	// ErrQueueClosed is returned by Queue.Enqueue after the queue has been stopped.
+	ErrQueueClosed = errors.New("webhook queue is closed")
+	// ErrJobDropped is passed to the DeadLetterSink for jobs that were still queued
+	// when WorkerPool.Stop gave up waiting or was called before WorkerPool.Start.
+	ErrJobDropped = errors.New("webhook job dropped when the queue stopped")
+	// ErrHandlerPanic wraps the value a handler panicked with. The job is retried as if
+	// the handler had returned the error.
+	ErrHandlerPanic = errors.New("webhook handler panicked")
+)
+
+// Job is an authenticated delivery waiting to be handled.
+// Event is not serialized: a durable Queue stores Delivery and the event is decoded
+// again from Delivery.Payload when Event is nil.
+type Job struct {
+	Delivery Delivery `json:"delivery"`
+	Event    Event    `json:"-"`
+}
+
+// Queue accepts jobs from a Dispatcher running in asynchronous mode, see Dispatcher.SetQueue.
+// Implementations must not block: return ErrQueueFull when there is no capacity.
+// Jobs are eventually passed to Dispatcher.Handle.
+type Queue interface {
+	Enqueue(ctx context.Context, job *Job) error
+}
+
+// DeadLetterSink receives jobs that failed on every attempt.
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, job *Job, err error)
+}
+
+// DeadLetterFunc adapts a function to DeadLetterSink.
+type DeadLetterFunc func(ctx context.Context, job *Job, err error)
+
+func (f DeadLetterFunc) DeadLetter(ctx context.Context, job *Job, err error) {
+	f(ctx, job, err)
+}
+
+// WorkerPoolOptions configures a WorkerPool. Zero values select the defaults.
+type WorkerPoolOptions struct {
+	// Workers is the number of goroutines handling jobs, 4 by default.
+	Workers int
+	// QueueSize bounds the number of jobs waiting per worker, 256 by default.
+	QueueSize int
+	// MaxAttempts is how often a job is tried before it is dead-lettered, 3 by default.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	DeadLetter     DeadLetterSink
+}
+
+// WorkerPool is an in-process Queue. Jobs of the same repository are always handled by
+// the same worker, one after another, so they are processed in delivery order. A job is
+// retried on that worker before the next job of the worker starts.
+type WorkerPool struct {
+	handle func(ctx context.Context, job *Job) error
+	opts   WorkerPoolOptions
+
+	shards []chan *Job
+	next   atomic.Uint32
+
+	mu      sync.RWMutex
+	started bool
+	closed  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// NewWorkerPool creates a pool calling handle, usually Dispatcher.Handle, for each job.
+// Call Start before enqueueing and Stop to drain it.
+func NewWorkerPool(handle func(ctx context.Context, job *Job) error, opts WorkerPoolOptions) *WorkerPool {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = defaultMaxBackoff
+		if opts.MaxBackoff < opts.InitialBackoff {
+			opts.MaxBackoff = opts.InitialBackoff
+		}
+	}
+
+	p := &WorkerPool{
+		handle: handle,
+		opts:   opts,
+		shards: make([]chan *Job, opts.Workers),
+	}
+	for i := range p.shards {
+		p.shards[i] = make(chan *Job, opts.QueueSize)
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// Start launches the workers. It does nothing when called again or after Stop.
+func (p *WorkerPool) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started || p.closed {
+		return
+	}
+	p.started = true
+	for _, shard := range p.shards {
+		p.workers.Add(1)
+		go p.work(shard)
+	}
+}
+
+// Stop stops accepting jobs and waits for the queued ones to be handled. When ctx is done
+// first, running handlers and backoff waits are cancelled and the remaining jobs are passed
+// to the DeadLetterSink with ErrJobDropped. So are all queued jobs when the pool was never
+// started, a stopped pool can not be started.
+func (p *WorkerPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, shard := range p.shards {
+			close(shard)
+		}
+	}
+	started := p.started
+	p.mu.Unlock()
+
+	if !started {
+		p.cancel()
+		for _, shard := range p.shards {
+			for job := range shard {
+				p.deadLetter(job, ErrJobDropped)
+			}
+		}
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of jobs waiting to be handled.
+func (p *WorkerPool) Len() int {
+	n := 0
+	for _, shard := range p.shards {
+		n += len(shard)
+	}
+	return n
+}
+
+func (p *WorkerPool) Enqueue(ctx context.Context, job *Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case p.shards[p.shard(job)] <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// shard picks the worker of the job's repository, or any worker for events without one.
+func (p *WorkerPool) shard(job *Job) int {
+	n := uint32(len(p.shards))
+	if job.Event == nil || job.Event.GetRepo() == nil {
+		return int(p.next.Add(1) % n)
+	}
+
+	h := fnv.New32a()
+	if org := job.Event.GetOrg(); org != nil {
+		_, _ = h.Write([]byte(*org))
+	}
+	_, _ = h.Write([]byte{'/'})
+	_, _ = h.Write([]byte(*job.Event.GetRepo()))
+	return int(h.Sum32() % n)
+}
+
+func (p *WorkerPool) work(shard chan *Job) {
+	defer p.workers.Done()
+
+	for job := range shard {
+		if p.ctx.Err() != nil {
+			p.deadLetter(job, ErrJobDropped)
+			continue
+		}
+		p.run(job)
+	}
+}
+
+func (p *WorkerPool) run(job *Job) {
+	backoff := p.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		job.Delivery.Attempt = attempt
+		err := p.try(job)
+		if err == nil {
+			return
+		}
+		if attempt >= p.opts.MaxAttempts || permanent(err) || !p.wait(backoff) {
+			p.deadLetter(job, err)
+			return
+		}
+
+		if backoff *= 2; backoff > p.opts.MaxBackoff {
+			backoff = p.opts.MaxBackoff
+		}
+	}
+}
+
+// try calls the handler, turning a panic into an error wrapping ErrHandlerPanic so that
+// one bad event does not take the process down.
+func (p *WorkerPool) try(job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrHandlerPanic, r, debug.Stack())
+		}
+	}()
+	return p.handle(p.ctx, job)
+}
+
+func (p *WorkerPool) deadLetter(job *Job, err error) {
+	if p.opts.DeadLetter != nil {
+		p.opts.DeadLetter.DeadLetter(context.WithoutCancel(p.ctx), job, err)
+	}
+}
+
+func (p *WorkerPool) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// permanent reports whether retrying can not help, as the payload itself is unusable.
+func permanent(err error) bool {
+	return errors.Is(err, ErrPayloadParse) || errors.Is(err, ErrUnknownEventType)
+}
@@ -0,0 +1,255 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func queueTestJob(repo string, n int) *Job {
+	org, name := "org", repo
+	action := strconv.Itoa(n)
+	return &Job{
+		Delivery: Delivery{EventType: releaseEvent, GUID: action},
+		Event:    &ReleaseEvent{Action: &action, Repository: &Project{Namespace: &org, Name: &name}},
+	}
+}
+
+func TestDispatcherAsync(t *testing.T) {
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+	pool := NewWorkerPool(d.Handle, WorkerPoolOptions{Workers: 1, QueueSize: 1})
+	d.SetQueue(pool)
+
+	handled := make(chan *Delivery, 2)
+	d.OnRelease(func(ctx context.Context, e *ReleaseEvent) error {
+		delivery, _ := DeliveryFromContext(ctx)
+		handled <- delivery
+		return nil
+	})
+
+	payload := readWebHookTestdata(t, webhookTestDataDir+"release.json", nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, payload))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, 1, pool.Len())
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, payload))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, queueFullErrorMessage+"\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, wikiPageEvent, []byte("{}")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	pool.Start()
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+	delivery := <-handled
+	assert.Equal(t, releaseEvent, delivery.EventType)
+	assert.Equal(t, 1, delivery.Attempt)
+	assert.Equal(t, payload, delivery.Payload)
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, payload))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, queueClosedErrorMessage+"\n", w.Body.String())
+	assert.True(t, errors.Is(pool.Enqueue(context.Background(), queueTestJob("a", 1)), ErrQueueClosed))
+
+	d.SetQueue(nil)
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, newDispatcherRequest(t, releaseEvent, payload))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, (<-handled).Attempt)
+}
+
+func TestWorkerPoolRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var dead []*Job
+	var deadErr error
+
+	handlerErr := errors.New("openapi unavailable")
+	pool := NewWorkerPool(func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[job.Delivery.GUID]++
+		assert.Equal(t, attempts[job.Delivery.GUID], job.Delivery.Attempt)
+		if job.Delivery.GUID == "1" && job.Delivery.Attempt < 3 {
+			return handlerErr
+		}
+		if job.Delivery.GUID == "2" {
+			return handlerErr
+		}
+		return nil
+	}, WorkerPoolOptions{
+		Workers:        2,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		DeadLetter: DeadLetterFunc(func(ctx context.Context, job *Job, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dead = append(dead, job)
+			deadErr = err
+		}),
+	})
+	pool.Start()
+
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 1)))
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("b", 2)))
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+
+	assert.Equal(t, 3, attempts["1"])
+	assert.Equal(t, 3, attempts["2"])
+	if assert.Equal(t, 1, len(dead)) {
+		assert.Equal(t, "2", dead[0].Delivery.GUID)
+		assert.Equal(t, handlerErr, deadErr)
+	}
+}
+
+func TestWorkerPoolOrdering(t *testing.T) {
+	var mu sync.Mutex
+	order := map[string][]string{}
+	pool := NewWorkerPool(func(ctx context.Context, job *Job) error {
+		time.Sleep(time.Microsecond)
+		mu.Lock()
+		defer mu.Unlock()
+		repo := *job.Event.GetRepo()
+		order[repo] = append(order[repo], *job.Event.GetAction())
+		return nil
+	}, WorkerPoolOptions{Workers: 4})
+	pool.Start()
+
+	var want []string
+	for i := 0; i < 50; i++ {
+		want = append(want, strconv.Itoa(i))
+		for _, repo := range []string{"a", "b", "c"} {
+			assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob(repo, i)))
+		}
+	}
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+
+	for _, repo := range []string{"a", "b", "c"} {
+		assert.Equal(t, want, order[repo], repo)
+	}
+}
+
+func TestWorkerPoolDecodeAndStop(t *testing.T) {
+	d, _ := NewDispatcher([]byte(dispatcherSignKey))
+	var tags []string
+	d.OnRelease(func(ctx context.Context, e *ReleaseEvent) error {
+		tags = append(tags, *e.Tag)
+		return nil
+	})
+
+	var deadErr error
+	pool := NewWorkerPool(d.Handle, WorkerPoolOptions{
+		Workers: 1,
+		DeadLetter: DeadLetterFunc(func(ctx context.Context, job *Job, err error) {
+			deadErr = err
+		}),
+	})
+	pool.Start()
+
+	payload := readWebHookTestdata(t, webhookTestDataDir+"release.json", nil)
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), &Job{Delivery: Delivery{EventType: releaseEvent, Payload: payload}}))
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), &Job{Delivery: Delivery{EventType: releaseEvent, Payload: []byte("[")}}))
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+	assert.True(t, errors.Is(deadErr, ErrPayloadParse))
+
+	// jobs still queued when Stop gives up are dead-lettered, not silently dropped
+	started := make(chan struct{})
+	var dropped []string
+	pool = NewWorkerPool(func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WorkerPoolOptions{
+		Workers: 1,
+		DeadLetter: DeadLetterFunc(func(ctx context.Context, job *Job, err error) {
+			if errors.Is(err, ErrJobDropped) {
+				dropped = append(dropped, job.Delivery.GUID)
+			}
+		}),
+	})
+	pool.Start()
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 1)))
+	<-started
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 2)))
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 3)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pool.Stop(ctx))
+	assert.Equal(t, []string{"2", "3"}, dropped)
+}
+
+func TestWorkerPoolStopBeforeStart(t *testing.T) {
+	var dropped []string
+	pool := NewWorkerPool(func(ctx context.Context, job *Job) error {
+		t.Error("jobs of a pool that was never started should not be handled")
+		return nil
+	}, WorkerPoolOptions{
+		Workers: 2,
+		DeadLetter: DeadLetterFunc(func(ctx context.Context, job *Job, err error) {
+			assert.True(t, errors.Is(err, ErrJobDropped))
+			dropped = append(dropped, job.Delivery.GUID)
+		}),
+	})
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 1)))
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("b", 2)))
+
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+	assert.ElementsMatch(t, []string{"1", "2"}, dropped)
+	assert.Equal(t, 0, pool.Len())
+	assert.Equal(t, ErrQueueClosed, pool.Enqueue(context.Background(), queueTestJob("a", 3)))
+
+	// a stopped pool is not started again
+	pool.Start()
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+}
+
+func TestWorkerPoolPanic(t *testing.T) {
+	var attempts int
+	var deadErr error
+	pool := NewWorkerPool(func(ctx context.Context, job *Job) error {
+		attempts++
+		panic("nil map")
+	}, WorkerPoolOptions{
+		Workers:        1,
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		DeadLetter: DeadLetterFunc(func(ctx context.Context, job *Job, err error) {
+			deadErr = err
+		}),
+	})
+	pool.Start()
+	assert.Equal(t, nil, pool.Enqueue(context.Background(), queueTestJob("a", 1)))
+	assert.Equal(t, nil, pool.Stop(context.Background()))
+
+	assert.Equal(t, 2, attempts)
+	assert.True(t, errors.Is(deadErr, ErrHandlerPanic))
+	assert.Contains(t, deadErr.Error(), "nil map")
+}
@@ -0,0 +1,467 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package relay forwards authenticated GitCode webhook deliveries to several downstream
+// services, re-signing the payload with the secret of each target.
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	defaultMaxPending     = 64
+	defaultRetryWindow    = time.Hour
+
+	gitcodeHeaderPrefix = "X-Gitcode-"
+
+	forwardErrorMessage = "502 Bad Gateway: Failed to forward webhook to some targets"
+	busyErrorMessage    = "503 Service Unavailable: Too many webhooks being forwarded"
+)
+
+var (
+	errorNilAuth   = errors.New("relay requires a GitCodeAuthentication")
+	errorNoTargets = errors.New("relay requires at least one target")
+)
+
+// Target is a downstream service deliveries are forwarded to.
+type Target struct {
+	// Name identifies the target in results and logs, the URL is used when empty.
+	Name string
+	URL  string
+	// Secret signs the forwarded payload. Without a secret the request carries no signature.
+	Secret []byte
+	// EventTypes limits the forwarded deliveries to these X-GitCode-Event values, all when empty.
+	EventTypes []string
+	// Timeout bounds each attempt, Options.Timeout when zero.
+	Timeout time.Duration
+	// MaxAttempts overrides Options.MaxAttempts when positive.
+	MaxAttempts int
+}
+
+func (t *Target) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.URL
+}
+
+func (t *Target) accepts(eventType string) bool {
+	return len(t.EventTypes) == 0 || slices.Contains(t.EventTypes, eventType)
+}
+
+// Options configures a Relay. Zero values select the defaults.
+type Options struct {
+	Client *http.Client
+	// Timeout bounds each attempt, 10s by default.
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is sent to a target, 3 by default.
+	// Network errors, 429 and 5xx responses are retried.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Async answers GitCode with 202 once the request is authenticated and forwards in the
+	// background, so slow targets do not make GitCode time out. Call Close to wait for them.
+	Async bool
+	// MaxPending bounds the deliveries forwarded in the background in async mode, 64 by
+	// default. Further requests are answered with 503 so GitCode retries them later.
+	MaxPending int
+	// RetryWindow is how long the targets that accepted a delivery are remembered in sync
+	// mode, 1 hour by default. When a failed target makes GitCode resend the delivery
+	// within that time, it is only forwarded to the targets that have not accepted it.
+	RetryWindow time.Duration
+	// OnResult is called with the outcome of each forwarded delivery and target. It is
+	// called from several goroutines at once and must be safe for concurrent use.
+	OnResult func(r Result)
+}
+
+// Delivery is an authenticated webhook request.
+type Delivery struct {
+	EventType string
+	GUID      string
+	// Header holds the X-GitCode-* headers of the original request.
+	Header  http.Header
+	Payload []byte
+}
+
+// Result is the outcome of forwarding a delivery to one target.
+type Result struct {
+	Target     string
+	Delivery   string
+	StatusCode int
+	Attempts   int
+	Err        error
+}
+
+// Relay is an http.Handler that authenticates GitCode webhook requests and forwards them
+// to its targets.
+//
+// Responses: 200 when every matching target accepted the delivery, 204 when no target
+// matches the event type, 502 when a target failed, and 202 in async mode, or 503 when
+// MaxPending deliveries are already being forwarded.
+// Authentication failures are answered as by GitCodeAuthentication.Auth.
+type Relay struct {
+	auth    *webhook.GitCodeAuthentication
+	targets []Target
+	opts    Options
+	now     func() time.Time
+
+	pending sync.WaitGroup
+	slots   chan struct{}
+
+	mu       sync.Mutex
+	accepted map[string]*acceptedBy // by delivery ID, see Options.RetryWindow
+	prunedAt time.Time
+}
+
+// acceptedBy records which targets accepted a delivery that some other target failed.
+type acceptedBy struct {
+	targets map[int]struct{}
+	at      time.Time
+}
+
+// New creates a Relay checking requests with a copy of the configuration of auth.
+func New(auth *webhook.GitCodeAuthentication, targets []Target, opts Options) (*Relay, error) {
+	if auth == nil {
+		return nil, errorNilAuth
+	}
+	if len(targets) == 0 {
+		return nil, errorNoTargets
+	}
+	for i := range targets {
+		u, err := url.Parse(targets[i].URL)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", targets[i].name(), err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("target %s: unsupported URL scheme %q", targets[i].name(), u.Scheme)
+		}
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.InitialBackoff)
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = defaultMaxPending
+	}
+	if opts.RetryWindow <= 0 {
+		opts.RetryWindow = defaultRetryWindow
+	}
+
+	return &Relay{
+		auth:     auth.Clone(),
+		targets:  slices.Clone(targets),
+		opts:     opts,
+		now:      time.Now,
+		slots:    make(chan struct{}, opts.MaxPending),
+		accepted: map[string]*acceptedBy{},
+	}, nil
+}
+
+func (rl *Relay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := rl.auth.Clone()
+	if err, unwritten := auth.Auth(w, r); err != nil {
+		var ae *webhook.AuthError
+		if unwritten && errors.As(err, &ae) {
+			http.Error(w, ae.Message, ae.StatusCode)
+		}
+		return
+	}
+
+	d := &Delivery{
+		EventType: auth.GetEventType(),
+		GUID:      auth.GetEventGUID(),
+		Header:    gitcodeHeaders(r.Header),
+		Payload:   auth.GetPayload().Bytes(),
+	}
+	if !rl.matches(d.EventType) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if rl.opts.Async {
+		select {
+		case rl.slots <- struct{}{}:
+		default:
+			_ = auth.ForgetDelivery()
+			http.Error(w, busyErrorMessage, http.StatusServiceUnavailable)
+			return
+		}
+
+		rl.pending.Add(1)
+		go func() {
+			defer func() {
+				<-rl.slots
+				rl.pending.Done()
+			}()
+			rl.Forward(context.WithoutCancel(r.Context()), d)
+		}()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	skip := rl.acceptedTargets(d.GUID)
+	failed := false
+	for i, res := range rl.forward(r.Context(), d, skip) {
+		switch {
+		case res == nil:
+		case res.Err != nil:
+			failed = true
+		default:
+			skip[i] = struct{}{}
+		}
+	}
+	if failed {
+		rl.remember(d.GUID, skip)
+		_ = auth.ForgetDelivery()
+		http.Error(w, forwardErrorMessage, http.StatusBadGateway)
+		return
+	}
+	rl.remember(d.GUID, nil)
+	w.WriteHeader(http.StatusOK)
+}
+
+// acceptedTargets returns the indexes of the targets that accepted the delivery with id
+// in an earlier request.
+func (rl *Relay) acceptedTargets(id string) map[int]struct{} {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	skip := map[int]struct{}{}
+	if a, ok := rl.accepted[id]; ok && rl.now().Sub(a.at) < rl.opts.RetryWindow {
+		for i := range a.targets {
+			skip[i] = struct{}{}
+		}
+	}
+	return skip
+}
+
+// remember records the targets that accepted the delivery with id, nil once all did.
+func (rl *Relay) remember(id string, targets map[int]struct{}) {
+	if id == "" {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	if now.Sub(rl.prunedAt) >= rl.opts.RetryWindow {
+		for k, a := range rl.accepted {
+			if now.Sub(a.at) >= rl.opts.RetryWindow {
+				delete(rl.accepted, k)
+			}
+		}
+		rl.prunedAt = now
+	}
+
+	if targets == nil {
+		delete(rl.accepted, id)
+		return
+	}
+	rl.accepted[id] = &acceptedBy{targets: targets, at: now}
+}
+
+// Close waits until the deliveries forwarded in async mode are done or ctx ends.
+func (rl *Relay) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		rl.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rl *Relay) matches(eventType string) bool {
+	for i := range rl.targets {
+		if rl.targets[i].accepts(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Forward sends d to every target accepting its event type in parallel and returns
+// the results in target order.
+func (rl *Relay) Forward(ctx context.Context, d *Delivery) []Result {
+	var out []Result
+	for _, res := range rl.forward(ctx, d, nil) {
+		if res != nil {
+			out = append(out, *res)
+		}
+	}
+	return out
+}
+
+// forward sends d to the targets accepting it whose index is not in skip, the result of
+// target i is at index i and nil for the targets that were not sent to.
+func (rl *Relay) forward(ctx context.Context, d *Delivery, skip map[int]struct{}) []*Result {
+	var (
+		wg      sync.WaitGroup
+		results = make([]*Result, len(rl.targets))
+	)
+	for i := range rl.targets {
+		t := &rl.targets[i]
+		if _, ok := skip[i]; ok || !t.accepts(d.EventType) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res := rl.send(ctx, t, d)
+			results[i] = &res
+			if rl.opts.OnResult != nil {
+				rl.opts.OnResult(res)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// send forwards d to t, retrying as configured.
+func (rl *Relay) send(ctx context.Context, t *Target, d *Delivery) Result {
+	res := Result{Target: t.name(), Delivery: d.GUID}
+
+	attempts := rl.opts.MaxAttempts
+	if t.MaxAttempts > 0 {
+		attempts = t.MaxAttempts
+	}
+	backoff := rl.opts.InitialBackoff
+	for {
+		res.Attempts++
+		var retry bool
+		res.StatusCode, retry, res.Err = rl.attempt(ctx, t, d)
+		if res.Err == nil || !retry || res.Attempts >= attempts || !wait(ctx, backoff) {
+			return res
+		}
+
+		if backoff *= 2; backoff > rl.opts.MaxBackoff {
+			backoff = rl.opts.MaxBackoff
+		}
+	}
+}
+
+// attempt makes one attempt and reports whether a failure may succeed when retried.
+func (rl *Relay) attempt(ctx context.Context, t *Target, d *Delivery) (int, bool, error) {
+	timeout := rl.opts.Timeout
+	if t.Timeout > 0 {
+		timeout = t.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := NewRequest(ctx, t, d)
+	if err != nil {
+		return 0, false, err
+	}
+
+	resp, err := rl.opts.Client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+	return resp.StatusCode, retry, fmt.Errorf("target %s answered %s", t.name(), resp.Status)
+}
+
+// NewRequest builds the request forwarding d to t: the original X-GitCode-* headers with
+// the signature replaced by one made with the secret of t.
+func NewRequest(ctx context.Context, t *Target, d *Delivery) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = d.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Del(webhook.HeaderSignature)
+	req.Header.Del(webhook.HeaderToken)
+	req.Header.Set(webhook.HeaderEvent, d.EventType)
+	if d.GUID != "" {
+		req.Header.Set(webhook.HeaderDelivery, d.GUID)
+	}
+	req.Header.Set("User-Agent", webhook.UserAgent)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if len(t.Secret) > 0 {
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(t.Secret, d.Payload))
+	}
+	return req, nil
+}
+
+func gitcodeHeaders(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), gitcodeHeaderPrefix) {
+			out[k] = slices.Clone(v)
+		}
+	}
+	return out
+}
+
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package relay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+	"github.com/opensourceways/go-gitcode/webhook/webhooktest"
+)
+
+const upstreamSecret = "upstream"
+
+func newRelay(t *testing.T, targets []Target, opts Options) *Relay {
+	var auth webhook.GitCodeAuthentication
+	assert.Equal(t, nil, auth.SetSignKey([]byte(upstreamSecret)))
+
+	opts.InitialBackoff = time.Millisecond
+	rl, err := New(&auth, targets, opts)
+	assert.Equal(t, nil, err)
+	return rl
+}
+
+// newTarget starts a downstream service verifying the relayed signature with secret.
+func newTarget(t *testing.T, secret string, status func(n int32) int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+
+		var auth webhook.GitCodeAuthentication
+		_ = auth.SetSignKey([]byte(secret))
+		if err, _ := auth.Auth(w, r); err != nil {
+			t.Errorf("relayed request rejected: %v", err)
+			return
+		}
+		assert.Equal(t, "delivery-1", r.Header.Get(webhook.HeaderDelivery))
+		assert.Equal(t, "kept", r.Header.Get("X-GitCode-Custom"))
+		assert.Equal(t, "", r.Header.Get("Authorization"))
+		w.WriteHeader(status(n))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newUpstreamRequest(t *testing.T, e webhook.Event) *http.Request {
+	req := webhooktest.NewEventRequest(e, []byte(upstreamSecret))
+	req.Header.Set(webhook.HeaderDelivery, "delivery-1")
+	req.Header.Set("X-GitCode-Custom", "kept")
+	req.Header.Set("Authorization", "dropped")
+	return req
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, []Target{{URL: "http://localhost"}}, Options{})
+	assert.Equal(t, errorNilAuth, err)
+
+	auth := new(webhook.GitCodeAuthentication)
+	_, err = New(auth, nil, Options{})
+	assert.Equal(t, errorNoTargets, err)
+
+	_, err = New(auth, []Target{{Name: "ftp", URL: "ftp://localhost"}}, Options{})
+	assert.Equal(t, `target ftp: unsupported URL scheme "ftp"`, err.Error())
+
+	rl, err := New(auth, []Target{{URL: "http://localhost"}}, Options{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, defaultTimeout, rl.opts.Timeout)
+	assert.Equal(t, defaultMaxAttempts, rl.opts.MaxAttempts)
+	assert.Equal(t, defaultMaxBackoff, rl.opts.MaxBackoff)
+}
+
+func TestRelayServeHTTP(t *testing.T) {
+	ok := func(int32) int { return http.StatusOK }
+	flaky := func(n int32) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}
+
+	all, allCalls := newTarget(t, "all", ok)
+	retried, retriedCalls := newTarget(t, "retried", flaky)
+	pushOnly, pushCalls := newTarget(t, "push", ok)
+
+	var results atomic.Int32
+	rl := newRelay(t, []Target{
+		{Name: "all", URL: all.URL, Secret: []byte("all")},
+		{Name: "retried", URL: retried.URL, Secret: []byte("retried"), EventTypes: []string{webhook.EventTypeIssue}},
+		{Name: "push", URL: pushOnly.URL, Secret: []byte("push"), EventTypes: []string{webhook.EventTypePush}},
+	}, Options{OnResult: func(r Result) {
+		results.Add(1)
+		assert.Equal(t, nil, r.Err)
+	}})
+
+	w := httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), allCalls.Load())
+	assert.Equal(t, int32(3), retriedCalls.Load())
+	assert.Equal(t, int32(0), pushCalls.Load())
+	assert.Equal(t, int32(2), results.Load())
+
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.ReleaseEvent("org", "repo", "v1", "create")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(2), allCalls.Load())
+
+	req := newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice"))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign([]byte("wrong"), []byte("{}")))
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, int32(2), allCalls.Load())
+}
+
+func TestRelayFailures(t *testing.T) {
+	rejecting, calls := newTarget(t, "s", func(int32) int { return http.StatusBadRequest })
+	rl := newRelay(t, []Target{
+		{URL: rejecting.URL, Secret: []byte("s"), EventTypes: []string{webhook.EventTypeIssue}},
+	}, Options{MaxAttempts: 5})
+
+	w := httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, forwardErrorMessage+"\n", w.Body.String())
+	// 4xx responses are not retried
+	assert.Equal(t, int32(1), calls.Load())
+
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.PushEvent("org", "repo", "refs/heads/main", "a", "b", "alice")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	unavailable, unavailableCalls := newTarget(t, "s", func(int32) int { return http.StatusBadGateway })
+	rl = newRelay(t, []Target{{URL: unavailable.URL, Secret: []byte("s"), MaxAttempts: 2}}, Options{})
+	res := rl.Forward(context.Background(), &Delivery{
+		EventType: webhook.EventTypeIssue,
+		GUID:      "delivery-1",
+		Header:    http.Header{"X-Gitcode-Custom": {"kept"}},
+		Payload:   []byte("{}"),
+	})
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, 2, res[0].Attempts)
+	assert.Equal(t, http.StatusBadGateway, res[0].StatusCode)
+	assert.NotNil(t, res[0].Err)
+	assert.Equal(t, int32(2), unavailableCalls.Load())
+}
+
+func TestRelayAsync(t *testing.T) {
+	release := make(chan struct{})
+	slow, calls := newTarget(t, "s", func(int32) int {
+		<-release
+		return http.StatusOK
+	})
+	rl := newRelay(t, []Target{{URL: slow.URL, Secret: []byte("s")}}, Options{Async: true, MaxPending: 1})
+
+	w := httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// the one slot is taken, GitCode is asked to retry later
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, rl.Close(ctx))
+
+	close(release)
+	assert.Equal(t, nil, rl.Close(context.Background()))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRelayRetryOnlyFailedTargets(t *testing.T) {
+	ok, okCalls := newTarget(t, "ok", func(int32) int { return http.StatusOK })
+	flaky, flakyCalls := newTarget(t, "flaky", func(n int32) int {
+		if n == 1 {
+			return http.StatusBadRequest
+		}
+		return http.StatusOK
+	})
+
+	auth := new(webhook.GitCodeAuthentication)
+	_ = auth.SetSignKey([]byte(upstreamSecret))
+	auth.SetDeliveryStore(webhook.NewMemoryDeliveryStore(10, 0), webhook.DuplicateDeliveryReject)
+	rl, err := New(auth, []Target{
+		{Name: "ok", URL: ok.URL, Secret: []byte("ok")},
+		{Name: "flaky", URL: flaky.URL, Secret: []byte("flaky")},
+	}, Options{})
+	assert.Equal(t, nil, err)
+
+	w := httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	// GitCode resends the delivery, only the target that failed gets it again
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), okCalls.Load())
+	assert.Equal(t, int32(2), flakyCalls.Load())
+	assert.Equal(t, 0, len(rl.accepted))
+
+	w = httptest.NewRecorder()
+	rl.ServeHTTP(w, newUpstreamRequest(t, webhooktest.IssueEvent("org", "repo", 1, "open", "alice")))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+type ReleaseCommit struct {
+	ID        *string            `json:"id,omitempty"`
+	Message   *string            `json:"message,omitempty"`
+	Title     *string            `json:"title,omitempty"`
+	Timestamp *openapi.Timestamp `json:"timestamp,omitempty"`
+	URL       *string            `json:"url,omitempty"`
+	Author    *CommitAuthor      `json:"author,omitempty"`
+}
+
+type ReleaseEvent struct {
+	UUID        *string            `json:"uuid,omitempty"`
+	ObjectKind  *string            `json:"object_kind,omitempty"`
+	ID          *json.Number       `json:"id,omitempty"`
+	Action      *string            `json:"action,omitempty"`
+	Name        *string            `json:"name,omitempty"`
+	Description *string            `json:"description,omitempty"`
+	Tag         *string            `json:"tag,omitempty"`
+	URL         *string            `json:"url,omitempty"`
+	User        *openapi.User      `json:"user,omitempty"`
+	Repository  *Project           `json:"project,omitempty"`
+	Commit      *ReleaseCommit     `json:"commit,omitempty"`
+	CreateTime  *openapi.Timestamp `json:"created_at,omitempty"`
+	ReleaseTime *openapi.Timestamp `json:"released_at,omitempty"`
+}
+
+func (rl *ReleaseEvent) GetAction() *string {
+	return rl.Action
+}
+func (rl *ReleaseEvent) GetActionDetail() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetState() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetOrg() *string {
+	if rl.Repository == nil {
+		return nil
+	}
+
+	return rl.Repository.Namespace
+}
+func (rl *ReleaseEvent) GetRepo() *string {
+	if rl.Repository == nil {
+		return nil
+	}
+
+	return rl.Repository.Name
+}
+func (rl *ReleaseEvent) GetHtmlURL() *string {
+	return rl.URL
+}
+
+// GetBase returns the tag the release is created from.
+func (rl *ReleaseEvent) GetBase() *string {
+	return rl.Tag
+}
+
+// GetHead returns the SHA of the released commit.
+func (rl *ReleaseEvent) GetHead() *string {
+	if rl.Commit == nil {
+		return nil
+	}
+
+	return rl.Commit.ID
+}
+func (rl *ReleaseEvent) GetNumber() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetID() *string {
+	if rl.ID == nil {
+		return nil
+	}
+
+	n := rl.ID.String()
+	return &n
+}
+func (rl *ReleaseEvent) GetAuthor() *string {
+	if rl.User == nil {
+		return nil
+	}
+
+	return rl.User.UserName
+}
+func (rl *ReleaseEvent) GetCommentID() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetCommentKind() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetComment() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetCommenter() *string {
+	return nil
+}
+func (rl *ReleaseEvent) GetCreateTime() *string {
+	if rl.CreateTime == nil {
+		return nil
+	}
+
+	return rl.CreateTime.ToString()
+}
+
+func (rl *ReleaseEvent) GetUpdateTime() *string {
+	if rl.ReleaseTime == nil {
+		return nil
+	}
+
+	return rl.ReleaseTime.ToString()
+}
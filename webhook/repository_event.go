@@ -0,0 +1,127 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+type RefChange struct {
+	Before *string `json:"before,omitempty"`
+	After  *string `json:"after,omitempty"`
+	Ref    *string `json:"ref,omitempty"`
+}
+
+type RepositoryEvent struct {
+	UUID        *string            `json:"uuid,omitempty"`
+	EventName   *string            `json:"event_name,omitempty"`
+	UserName    *string            `json:"user_name,omitempty"`
+	Author      *string            `json:"user_username,omitempty"`
+	UserEmail   *string            `json:"user_email,omitempty"`
+	Repository  *Project           `json:"project,omitempty"`
+	Changes     []*RefChange       `json:"changes,omitempty"`
+	Refs        []string           `json:"refs,omitempty"`
+	CreateTime  *openapi.Timestamp `json:"created_at,omitempty"`
+	UpdatedTime *openapi.Timestamp `json:"updated_at,omitempty"`
+}
+
+// GetAction returns the event name, such as repository_update.
+func (rp *RepositoryEvent) GetAction() *string {
+	return rp.EventName
+}
+func (rp *RepositoryEvent) GetActionDetail() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetState() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetOrg() *string {
+	if rp.Repository == nil {
+		return nil
+	}
+
+	return rp.Repository.Namespace
+}
+func (rp *RepositoryEvent) GetRepo() *string {
+	if rp.Repository == nil {
+		return nil
+	}
+
+	return rp.Repository.Name
+}
+func (rp *RepositoryEvent) GetHtmlURL() *string {
+	if rp.Repository == nil {
+		return nil
+	}
+
+	return rp.Repository.HTMLURL
+}
+
+// GetBase returns the first updated ref.
+func (rp *RepositoryEvent) GetBase() *string {
+	if len(rp.Changes) == 0 {
+		return nil
+	}
+
+	return rp.Changes[0].Ref
+}
+
+// GetHead returns the SHA the first updated ref points to.
+func (rp *RepositoryEvent) GetHead() *string {
+	if len(rp.Changes) == 0 {
+		return nil
+	}
+
+	return rp.Changes[0].After
+}
+func (rp *RepositoryEvent) GetNumber() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetID() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetAuthor() *string {
+	if rp.Author != nil {
+		return rp.Author
+	}
+
+	return rp.UserName
+}
+func (rp *RepositoryEvent) GetCommentID() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetCommentKind() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetComment() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetCommenter() *string {
+	return nil
+}
+func (rp *RepositoryEvent) GetCreateTime() *string {
+	if rp.CreateTime == nil {
+		return nil
+	}
+
+	return rp.CreateTime.ToString()
+}
+
+func (rp *RepositoryEvent) GetUpdateTime() *string {
+	if rp.UpdatedTime == nil {
+		return nil
+	}
+
+	return rp.UpdatedTime.ToString()
+}
@@ -0,0 +1,42 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Headers sent by GitCode with every webhook delivery.
+const (
+	HeaderEvent     = "X-GitCode-Event"
+	HeaderDelivery  = "X-GitCode-Delivery"
+	HeaderSignature = "X-GitCode-Signature-256"
+	HeaderToken     = "X-GitCode-Token"
+	HeaderTimestamp = "X-GitCode-Timestamp"
+
+	// UserAgent is the User-Agent GitCode sends, which Auth requires.
+	UserAgent = "git-gitcode-hook"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the X-GitCode-Signature-256 value for payload signed with secret,
+// so tools can produce deliveries that Auth accepts.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,30 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte("{\"object_kind\":\"push\"}")
+	token := Sign([]byte("1234"), payload)
+
+	assert.Equal(t, 1, matchSignature(token, []string{"1234"}, bytes.NewBuffer(payload)))
+	assert.Equal(t, 0, matchSignature(token, []string{"4321"}, bytes.NewBuffer(payload)))
+	assert.Equal(t, "sha256=", token[:len(signaturePrefix)])
+}
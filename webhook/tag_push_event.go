@@ -0,0 +1,146 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"strings"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+)
+
+const (
+	zeroSHA = "0000000000000000000000000000000000000000"
+
+	refActionCreate = "create"
+	refActionDelete = "delete"
+	refActionUpdate = "update"
+
+	tagRefPrefix = "refs/tags/"
+)
+
+type TagPushEvent struct {
+	UUID              *string            `json:"uuid,omitempty"`
+	EventType         *string            `json:"event_name,omitempty"`
+	ObjectKind        *string            `json:"object_kind,omitempty"`
+	Before            *string            `json:"before,omitempty"`
+	After             *string            `json:"after,omitempty"`
+	Ref               *string            `json:"ref,omitempty"`
+	CheckoutSHA       *string            `json:"checkout_sha,omitempty"`
+	Message           *string            `json:"message,omitempty"`
+	UserName          *string            `json:"user_name,omitempty"`
+	Author            *string            `json:"user_username,omitempty"`
+	Repository        *Project           `json:"project,omitempty"`
+	Commits           []*PushCommit      `json:"commits,omitempty"`
+	TotalCommitsCount *int               `json:"total_commits_count,omitempty"`
+	CreateTime        *openapi.Timestamp `json:"created_at,omitempty"`
+	UpdatedTime       *openapi.Timestamp `json:"updated_at,omitempty"`
+}
+
+// refAction derives create, delete or update from the SHAs before and after a ref changed.
+func refAction(before, after *string) *string {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	action := refActionUpdate
+	switch {
+	case *before == zeroSHA:
+		action = refActionCreate
+	case *after == zeroSHA:
+		action = refActionDelete
+	}
+	return &action
+}
+
+func (p *TagPushEvent) GetAction() *string {
+	return refAction(p.Before, p.After)
+}
+func (p *TagPushEvent) GetActionDetail() *string {
+	return p.Message
+}
+func (p *TagPushEvent) GetState() *string {
+	return nil
+}
+func (p *TagPushEvent) GetOrg() *string {
+	if p.Repository == nil {
+		return nil
+	}
+
+	return p.Repository.Namespace
+}
+func (p *TagPushEvent) GetRepo() *string {
+	if p.Repository == nil {
+		return nil
+	}
+
+	return p.Repository.Name
+}
+func (p *TagPushEvent) GetHtmlURL() *string {
+	if p.Repository == nil {
+		return nil
+	}
+
+	return p.Repository.HTMLURL
+}
+
+// GetBase returns the tag name.
+func (p *TagPushEvent) GetBase() *string {
+	if p.Ref == nil {
+		return nil
+	}
+
+	tag := strings.TrimPrefix(*p.Ref, tagRefPrefix)
+	return &tag
+}
+
+// GetHead returns the SHA the tag points to after the push.
+func (p *TagPushEvent) GetHead() *string {
+	return p.After
+}
+func (p *TagPushEvent) GetNumber() *string {
+	return nil
+}
+func (p *TagPushEvent) GetID() *string {
+	return p.CheckoutSHA
+}
+func (p *TagPushEvent) GetAuthor() *string {
+	return p.Author
+}
+func (p *TagPushEvent) GetCommentID() *string {
+	return nil
+}
+func (p *TagPushEvent) GetCommentKind() *string {
+	return nil
+}
+func (p *TagPushEvent) GetComment() *string {
+	return nil
+}
+func (p *TagPushEvent) GetCommenter() *string {
+	return nil
+}
+func (p *TagPushEvent) GetCreateTime() *string {
+	if p.CreateTime == nil {
+		return nil
+	}
+
+	return p.CreateTime.ToString()
+}
+
+func (p *TagPushEvent) GetUpdateTime() *string {
+	if p.UpdatedTime == nil {
+		return nil
+	}
+
+	return p.UpdatedTime.ToString()
+}
@@ -0,0 +1,227 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhooktest
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+// The builders below return events with the fields handlers usually read filled in.
+// Tests can set further fields on the returned structs before building a request.
+
+// ZeroSHA is the SHA GitCode sends for the missing side of a created or deleted ref.
+const ZeroSHA = "0000000000000000000000000000000000000000"
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func project(org, repo string) *webhook.Project {
+	return &webhook.Project{
+		Name:      ptr(repo),
+		Namespace: ptr(org),
+		Path:      ptr(org + "/" + repo),
+		HTMLURL:   ptr("https://gitcode.com/" + org + "/" + repo),
+	}
+}
+
+func user(login string) *openapi.User {
+	return &openapi.User{Login: ptr(login), UserName: ptr(login), Name: ptr(login)}
+}
+
+func number(n int) *json.Number {
+	return ptr(json.Number(strconv.Itoa(n)))
+}
+
+func webURL(org, repo, kind string, n int) *string {
+	return ptr("https://gitcode.com/" + org + "/" + repo + "/" + kind + "/" + strconv.Itoa(n))
+}
+
+// IssueEvent builds an "Issue Hook" event for issue number opened by author.
+func IssueEvent(org, repo string, n int, action, author string) *webhook.IssueEvent {
+	return &webhook.IssueEvent{
+		ObjectKind: ptr("issue"),
+		Attributes: &webhook.Attributes{
+			ID:     number(n),
+			Action: ptr(action),
+			State:  ptr("opened"),
+			Number: ptr(n),
+			URL:    webURL(org, repo, "issues", n),
+		},
+		User:       user(author),
+		Repository: project(org, repo),
+		Issue:      &webhook.IssuePart{Action: ptr(action), Number: ptr(n), Author: user(author), ID: number(n)},
+	}
+}
+
+// PullRequestEvent builds a "Merge Request Hook" event for pull request number from head into base.
+func PullRequestEvent(org, repo string, n int, action, author, base, head string) *webhook.PullRequestEvent {
+	return &webhook.PullRequestEvent{
+		ObjectKind: ptr("merge_request"),
+		Attributes: &webhook.Attributes{
+			ID:           number(n),
+			Action:       ptr(action),
+			State:        ptr("opened"),
+			Number:       ptr(n),
+			URL:          webURL(org, repo, "merge_requests", n),
+			TargetBranch: ptr(base),
+			SourceBranch: ptr(head),
+			Source:       project(org, repo),
+		},
+		User:       user(author),
+		Repository: project(org, repo),
+	}
+}
+
+// PullRequestNoteEvent builds a "Note Hook" event for a comment on pull request number.
+func PullRequestNoteEvent(org, repo string, n int, commenter, comment string) *webhook.NoteEvent {
+	return &webhook.NoteEvent{
+		ObjectKind: ptr("note"),
+		Attributes: &webhook.Attributes{
+			CommentID:   ptr("note-" + strconv.Itoa(n)),
+			Comment:     ptr(comment),
+			CommentKind: ptr("MergeRequest"),
+			URL:         webURL(org, repo, "merge_requests", n),
+		},
+		User:       user(commenter),
+		Repository: project(org, repo),
+		PR:         &webhook.PRPart{Number: ptr(n), ID: number(n), Source: project(org, repo)},
+	}
+}
+
+// IssueNoteEvent builds a "Note Hook" event for a comment on issue number.
+func IssueNoteEvent(org, repo string, n int, commenter, comment string) *webhook.NoteEvent {
+	return &webhook.NoteEvent{
+		ObjectKind: ptr("note"),
+		Attributes: &webhook.Attributes{
+			CommentID:   ptr("note-" + strconv.Itoa(n)),
+			Comment:     ptr(comment),
+			CommentKind: ptr("Issue"),
+			URL:         webURL(org, repo, "issues", n),
+		},
+		User:       user(commenter),
+		Repository: project(org, repo),
+		Issue:      &webhook.IssuePart{Number: ptr(n), ID: number(n)},
+	}
+}
+
+// PushEvent builds a "Push Hook" event moving ref from before to after.
+// Use ZeroSHA as before or after for a branch creation or deletion.
+func PushEvent(org, repo, ref, before, after, pusher string) *webhook.PushEvent {
+	return &webhook.PushEvent{
+		ObjectKind:  ptr("push"),
+		Before:      ptr(before),
+		After:       ptr(after),
+		Ref:         ptr(ref),
+		CheckoutSHA: ptr(after),
+		Author:      ptr(pusher),
+		UserName:    ptr(pusher),
+		Repository:  project(org, repo),
+	}
+}
+
+// TagPushEvent builds a "Tag Push Hook" event creating tag at sha.
+func TagPushEvent(org, repo, tag, sha, pusher string) *webhook.TagPushEvent {
+	return &webhook.TagPushEvent{
+		ObjectKind:  ptr("tag_push"),
+		Before:      ptr(ZeroSHA),
+		After:       ptr(sha),
+		Ref:         ptr("refs/tags/" + tag),
+		CheckoutSHA: ptr(sha),
+		Author:      ptr(pusher),
+		Repository:  project(org, repo),
+	}
+}
+
+// ReleaseEvent builds a "Release Hook" event for the release of tag.
+func ReleaseEvent(org, repo, tag, action string) *webhook.ReleaseEvent {
+	return &webhook.ReleaseEvent{
+		ObjectKind: ptr("release"),
+		Action:     ptr(action),
+		Name:       ptr(tag),
+		Tag:        ptr(tag),
+		URL:        ptr("https://gitcode.com/" + org + "/" + repo + "/releases/" + tag),
+		Repository: project(org, repo),
+	}
+}
+
+// PipelineEvent builds a "Pipeline Hook" event for a pipeline on ref at sha.
+func PipelineEvent(org, repo string, id int, ref, sha, status string) *webhook.PipelineEvent {
+	return &webhook.PipelineEvent{
+		ObjectKind: ptr("pipeline"),
+		Attributes: &webhook.PipelineAttributes{
+			ID:     number(id),
+			Ref:    ptr(ref),
+			SHA:    ptr(sha),
+			Status: ptr(status),
+			URL:    webURL(org, repo, "pipelines", id),
+		},
+		Repository: project(org, repo),
+	}
+}
+
+// JobEvent builds a "Job Hook" event for job name on ref at sha.
+func JobEvent(org, repo string, id int, name, ref, sha, status string) *webhook.JobEvent {
+	return &webhook.JobEvent{
+		ObjectKind: ptr("build"),
+		ID:         number(id),
+		Name:       ptr(name),
+		Ref:        ptr(ref),
+		SHA:        ptr(sha),
+		Status:     ptr(status),
+		URL:        webURL(org, repo, "jobs", id),
+		Repository: project(org, repo),
+	}
+}
+
+// WikiPageEvent builds a "Wiki Page Hook" event for the page slug.
+func WikiPageEvent(org, repo, slug, action, author string) *webhook.WikiPageEvent {
+	return &webhook.WikiPageEvent{
+		ObjectKind: ptr("wiki_page"),
+		User:       user(author),
+		Repository: project(org, repo),
+		Attributes: &webhook.WikiPageAttributes{
+			Title:  ptr(slug),
+			Slug:   ptr(slug),
+			Action: ptr(action),
+			URL:    ptr("https://gitcode.com/" + org + "/" + repo + "/wiki/" + slug),
+		},
+	}
+}
+
+// MemberEvent builds a "Member Hook" event, such as user_add_to_team, for member.
+func MemberEvent(org, repo, eventName, member, accessLevel string) *webhook.MemberEvent {
+	return &webhook.MemberEvent{
+		EventName:   ptr(eventName),
+		AccessLevel: ptr(accessLevel),
+		ProjectName: ptr(repo),
+		ProjectPath: ptr(org + "/" + repo),
+		UserName:    ptr(member),
+		Repository:  project(org, repo),
+	}
+}
+
+// RepositoryEvent builds a "Repository Update Hook" event moving ref from before to after.
+func RepositoryEvent(org, repo, ref, before, after string) *webhook.RepositoryEvent {
+	return &webhook.RepositoryEvent{
+		EventName:  ptr("repository_update"),
+		Repository: project(org, repo),
+		Changes:    []*webhook.RefChange{{Before: ptr(before), After: ptr(after), Ref: ptr(ref)}},
+		Refs:       []string{ref},
+	}
+}
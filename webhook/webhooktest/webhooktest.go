@@ -0,0 +1,88 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package webhooktest builds signed GitCode webhook requests and event payloads
+// for testing handlers that use webhook.GitCodeAuthentication or webhook.Dispatcher.
+package webhooktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+const target = "http://localhost/webhook"
+
+var deliverySeq atomic.Int64
+
+// NewSignedRequest returns a POST request carrying payload with the headers GitCode sends:
+// event type, a unique delivery ID, timestamp, User-Agent and the HMAC signature made with secret.
+func NewSignedRequest(eventType string, payload, secret []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	req.Header.Set("User-Agent", webhook.UserAgent)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, eventType)
+	req.Header.Set(webhook.HeaderDelivery, fmt.Sprintf("webhooktest-%d", deliverySeq.Add(1)))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, payload))
+	return req
+}
+
+// NewEventRequest marshals e and returns it as a signed request for the matching event type.
+func NewEventRequest(e webhook.Event, secret []byte) *http.Request {
+	return NewSignedRequest(webhook.EventTypeOf(e), Payload(e), secret)
+}
+
+// Payload marshals e to JSON, panicking on failure since the event types always marshal.
+func Payload(e webhook.Event) []byte {
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Serve passes r to h and returns the recorded response.
+func Serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// AssertResponse reports an error on t unless w has the status code and its body
+// contains each of the given strings. It returns whether the assertion passed.
+func AssertResponse(t testing.TB, w *httptest.ResponseRecorder, status int, bodyContains ...string) bool {
+	t.Helper()
+
+	ok := true
+	if w.Code != status {
+		t.Errorf("webhook response status = %d, want %d, body: %q", w.Code, status, w.Body.String())
+		ok = false
+	}
+	for _, s := range bodyContains {
+		if !strings.Contains(w.Body.String(), s) {
+			t.Errorf("webhook response body %q does not contain %q", w.Body.String(), s)
+			ok = false
+		}
+	}
+	return ok
+}
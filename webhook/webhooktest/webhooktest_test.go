@@ -0,0 +1,111 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhooktest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opensourceways/go-gitcode/webhook"
+)
+
+var secret = []byte("webhooktest-secret")
+
+type recordingTB struct {
+	testing.TB
+	errors int
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors++
+}
+
+func TestNewSignedRequest(t *testing.T) {
+	req := NewSignedRequest(webhook.EventTypePush, []byte("{}"), secret)
+
+	a := new(webhook.GitCodeAuthentication)
+	_ = a.SetSignKey(secret)
+	err, _ := a.Auth(httptest.NewRecorder(), req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, webhook.EventTypePush, a.GetEventType())
+
+	other := NewSignedRequest(webhook.EventTypePush, []byte("{}"), secret)
+	assert.NotEqual(t, req.Header.Get(webhook.HeaderDelivery), other.Header.Get(webhook.HeaderDelivery))
+}
+
+func TestEventBuilders(t *testing.T) {
+	sha := "2f03691536b8ef5ee2710b8605b9d16ccc96b52f"
+	events := []webhook.Event{
+		IssueEvent("org", "repo", 1, "open", "alice"),
+		PullRequestEvent("org", "repo", 2, "open", "alice", "main", "feature"),
+		PullRequestNoteEvent("org", "repo", 2, "bob", "/lgtm"),
+		IssueNoteEvent("org", "repo", 1, "bob", "/close"),
+		PushEvent("org", "repo", "refs/heads/main", ZeroSHA, sha, "alice"),
+		TagPushEvent("org", "repo", "v1.0.0", sha, "alice"),
+		ReleaseEvent("org", "repo", "v1.0.0", "create"),
+		PipelineEvent("org", "repo", 3, "main", sha, "success"),
+		JobEvent("org", "repo", 4, "unit-test", "main", sha, "failed"),
+		WikiPageEvent("org", "repo", "home", "create", "alice"),
+		MemberEvent("org", "repo", "user_add_to_team", "carol", "Developer"),
+		RepositoryEvent("org", "repo", "refs/heads/main", ZeroSHA, sha),
+	}
+
+	d, _ := webhook.NewDispatcher(secret)
+	var got []webhook.Event
+	for _, eventType := range []string{
+		webhook.EventTypeIssue, webhook.EventTypePullRequest, webhook.EventTypeNote, webhook.EventTypePush,
+		webhook.EventTypeTagPush, webhook.EventTypeRelease, webhook.EventTypePipeline, webhook.EventTypeJob,
+		webhook.EventTypeWikiPage, webhook.EventTypeMember, webhook.EventTypeRepository,
+	} {
+		d.OnEvent(eventType, func(ctx context.Context, e webhook.Event) error {
+			got = append(got, e)
+			return nil
+		})
+	}
+
+	for _, e := range events {
+		w := Serve(d, NewEventRequest(e, secret))
+		AssertResponse(t, w, http.StatusOK)
+	}
+
+	if !assert.Equal(t, len(events), len(got)) {
+		return
+	}
+	for i, e := range got {
+		assert.Equal(t, "org", *e.GetOrg(), webhook.EventTypeOf(e))
+		assert.Equal(t, "repo", *e.GetRepo(), webhook.EventTypeOf(e))
+		assert.Equal(t, Payload(events[i]), Payload(e))
+	}
+	assert.Equal(t, "/lgtm", *got[2].GetComment())
+	assert.Equal(t, "create", *got[4].GetAction())
+	assert.Equal(t, "v1.0.0", *got[5].GetBase())
+}
+
+func TestAssertResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	http.Error(w, "403 Forbidden: Invalid X-GitCode-Token", http.StatusForbidden)
+
+	tb := &recordingTB{TB: t}
+	assert.Equal(t, true, AssertResponse(tb, w, http.StatusForbidden, "Invalid X-GitCode-Token"))
+	assert.Equal(t, 0, tb.errors)
+
+	assert.Equal(t, false, AssertResponse(tb, w, http.StatusOK, "accepted", "Forbidden"))
+	assert.Equal(t, 2, tb.errors)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import "github.com/opensourceways/go-gitcode/openapi"
+
+type WikiPageAttributes struct {
+	Title       *string            `json:"title,omitempty"`
+	Content     *string            `json:"content,omitempty"`
+	Format      *string            `json:"format,omitempty"`
+	Message     *string            `json:"message,omitempty"`
+	Slug        *string            `json:"slug,omitempty"`
+	URL         *string            `json:"url,omitempty"`
+	Action      *string            `json:"action,omitempty"`
+	CreateTime  *openapi.Timestamp `json:"created_at,omitempty"`
+	UpdatedTime *openapi.Timestamp `json:"updated_at,omitempty"`
+}
+
+type WikiPageEvent struct {
+	UUID       *string             `json:"uuid,omitempty"`
+	ObjectKind *string             `json:"object_kind,omitempty"`
+	User       *openapi.User       `json:"user,omitempty"`
+	Repository *Project            `json:"project,omitempty"`
+	Attributes *WikiPageAttributes `json:"object_attributes,omitempty"`
+}
+
+func (wp *WikiPageEvent) GetAction() *string {
+	if wp.Attributes == nil {
+		return nil
+	}
+
+	return wp.Attributes.Action
+}
+
+// GetActionDetail returns the commit message of the wiki change.
+func (wp *WikiPageEvent) GetActionDetail() *string {
+	if wp.Attributes == nil {
+		return nil
+	}
+
+	return wp.Attributes.Message
+}
+func (wp *WikiPageEvent) GetState() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetOrg() *string {
+	if wp.Repository == nil {
+		return nil
+	}
+
+	return wp.Repository.Namespace
+}
+func (wp *WikiPageEvent) GetRepo() *string {
+	if wp.Repository == nil {
+		return nil
+	}
+
+	return wp.Repository.Name
+}
+func (wp *WikiPageEvent) GetHtmlURL() *string {
+	if wp.Attributes == nil {
+		return nil
+	}
+
+	return wp.Attributes.URL
+}
+func (wp *WikiPageEvent) GetBase() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetHead() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetNumber() *string {
+	return nil
+}
+
+// GetID returns the slug of the wiki page.
+func (wp *WikiPageEvent) GetID() *string {
+	if wp.Attributes == nil {
+		return nil
+	}
+
+	return wp.Attributes.Slug
+}
+func (wp *WikiPageEvent) GetAuthor() *string {
+	if wp.User == nil {
+		return nil
+	}
+
+	return wp.User.UserName
+}
+func (wp *WikiPageEvent) GetCommentID() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetCommentKind() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetComment() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetCommenter() *string {
+	return nil
+}
+func (wp *WikiPageEvent) GetCreateTime() *string {
+	if wp.Attributes == nil || wp.Attributes.CreateTime == nil {
+		return nil
+	}
+
+	return wp.Attributes.CreateTime.ToString()
+}
+
+func (wp *WikiPageEvent) GetUpdateTime() *string {
+	if wp.Attributes == nil || wp.Attributes.UpdatedTime == nil {
+		return nil
+	}
+
+	return wp.Attributes.UpdatedTime.ToString()
+}